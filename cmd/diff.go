@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/nrfta/ddsl/log"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: parser.ShortDesc("diff"),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("additional arguments required, use -h for help")
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.AddCommand(diffSchemas)
+	diffCmd.AddCommand(diffSchemaItems)
+	diffCmd.AddCommand(diffTables)
+	diffCmd.AddCommand(diffViews)
+	diffCmd.AddCommand(diffFunctions)
+	diffCmd.AddCommand(diffProcedures)
+	diffCmd.AddCommand(diffTypes)
+}
+
+func runDiffCmd(cmd *cobra.Command, args []string) {
+	command := fmt.Sprintf("diff %s", cmd.Use)
+	if len(args) > 0 {
+		command += " "
+	}
+	command += strings.Join(args, " ")
+
+	code, err := runCLICommand(command)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffFunctions represents the diff functions command
+var diffFunctions = &cobra.Command{
+	Use:   "functions",
+	Short: parser.ShortDesc("diff functions"),
+	Long: `Usage: diff functions [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff functions
+  diff functions in foo_schema
+  diff functions except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
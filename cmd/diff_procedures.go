@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffProcedures represents the diff procedures command
+var diffProcedures = &cobra.Command{
+	Use:   "procedures",
+	Short: parser.ShortDesc("diff procedures"),
+	Long: `Usage: diff procedures [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff procedures
+  diff procedures in foo_schema
+  diff procedures except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffSchemaItems represents the diff schema-items command
+var diffSchemaItems = &cobra.Command{
+	Use:   "schema-items",
+	Short: parser.ShortDesc("diff schema-items"),
+	Long: `Usage: diff schema-items [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff schema-items
+  diff schema-items in foo_schema
+  diff schema-items except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
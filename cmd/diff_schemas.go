@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffSchemas represents the diff schemas command
+var diffSchemas = &cobra.Command{
+	Use:   "schemas",
+	Short: parser.ShortDesc("diff schemas"),
+	Long: `Usage: diff schemas [except <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff schemas
+  diff schemas except public
+`,
+	Run: runDiffCmd,
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffTables represents the diff tables command
+var diffTables = &cobra.Command{
+	Use:   "tables",
+	Short: parser.ShortDesc("diff tables"),
+	Long: `Usage: diff tables [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff tables
+  diff tables in foo_schema
+  diff tables except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffTypes represents the diff types command
+var diffTypes = &cobra.Command{
+	Use:   "types",
+	Short: parser.ShortDesc("diff types"),
+	Long: `Usage: diff types [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff types
+  diff types in foo_schema
+  diff types except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
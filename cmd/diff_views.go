@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// diffViews represents the diff views command
+var diffViews = &cobra.Command{
+	Use:   "views",
+	Short: parser.ShortDesc("diff views"),
+	Long: `Usage: diff views [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  diff views
+  diff views in foo_schema
+  diff views except in foo_schema,bar_schema
+`,
+	Run: runDiffCmd,
+}
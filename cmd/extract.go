@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/nrfta/ddsl/log"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract",
+	Short: parser.ShortDesc("extract"),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("additional arguments required, use -h for help")
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+	extractCmd.AddCommand(extractSchemas)
+	extractCmd.AddCommand(extractSchema)
+}
+
+func runExtractCmd(cmd *cobra.Command, args []string) {
+	command := fmt.Sprintf("extract %s", cmd.Use)
+	if len(args) > 0 {
+		command += " "
+	}
+	command += strings.Join(args, " ")
+
+	code, err := runCLICommand(command)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	os.Exit(code)
+}
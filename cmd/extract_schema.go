@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// extractSchema represents the extract schema command
+var extractSchema = &cobra.Command{
+	Use:   "schema",
+	Short: parser.ShortDesc("extract schema"),
+	Long: `Usage: extract schema <schema_name>[,<schema_name>...];
+
+Examples:
+  extract schema foo_schema
+  extract schema foo_schema,bar_schema
+`,
+	Run: runExtractCmd,
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// extractSchemas represents the extract schemas command
+var extractSchemas = &cobra.Command{
+	Use:   "schemas",
+	Short: parser.ShortDesc("extract schemas"),
+	Long: `Usage: extract schemas [except <schema_name>[,<schema_name>...]];
+
+Examples:
+  extract schemas
+  extract schemas except public
+`,
+	Run: runExtractCmd,
+}
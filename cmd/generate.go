@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/nrfta/ddsl/log"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: parser.ShortDesc("generate"),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("additional arguments required, use -h for help")
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.AddCommand(generateDrops)
+}
+
+func runGenerateCmd(cmd *cobra.Command, args []string) {
+	command := fmt.Sprintf("generate %s", cmd.Use)
+	if len(args) > 0 {
+		command += " "
+	}
+	command += strings.Join(args, " ")
+
+	code, err := runCLICommand(command)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// generateDrops represents the generate drops command
+var generateDrops = &cobra.Command{
+	Use:   "drops",
+	Short: parser.ShortDesc("generate drops"),
+	Long: `Usage: generate drops [check] [ (in | except in) <schema_name>[,<schema_name>...]];
+
+Examples:
+  generate drops
+  generate drops in foo_schema
+  generate drops check
+  generate drops check except in foo_schema,bar_schema
+`,
+	Run: runGenerateCmd,
+}
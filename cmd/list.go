@@ -22,7 +22,9 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.AddCommand(listRoles)
 	listCmd.AddCommand(listSchemas)
+	listCmd.AddCommand(listExtensions)
 	listCmd.AddCommand(listForeignKeys)
 	listCmd.AddCommand(listSchemaItems)
 	listCmd.AddCommand(listTables)
@@ -30,6 +32,10 @@ func init() {
 	listCmd.AddCommand(listFunctions)
 	listCmd.AddCommand(listProcedures)
 	listCmd.AddCommand(listTypes)
+	listCmd.AddCommand(listColumns)
+	listCmd.AddCommand(listIndexes)
+	listCmd.AddCommand(listConstraints)
+	listCmd.AddCommand(listTriggers)
 
 	viper.BindEnv("output_format")
 
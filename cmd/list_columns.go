@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listColumns represents the columns command
+var listColumns = &cobra.Command{
+	Use:   "columns",
+	Short: parser.ShortDesc("list columns"),
+	Long: `Usage: list columns on <schema_name.table_or_view_name>[,<schema_name.table_or_view_name>...];
+
+Examples:
+  list columns on foo_schema.bar_table
+  list columns on foo_schema.bar_table,foo_schema.baz_table
+`,
+	Run: runListCmd,
+}
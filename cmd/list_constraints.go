@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listConstraints represents the constraints command
+var listConstraints = &cobra.Command{
+	Use:   "constraints",
+	Short: parser.ShortDesc("list constraints"),
+	Long: `Usage: list constraints on <schema_name.table_name>[,<schema_name.table_name>...];
+
+Examples:
+  list constraints on foo_schema.bar_table
+  list constraints on foo_schema.bar_table,foo_schema.baz_table
+`,
+	Run: runListCmd,
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listExtensions represents the extensions command
+var listExtensions = &cobra.Command{
+	Use:   "extensions",
+	Short: parser.ShortDesc("list extensions"),
+	Long: `Usage: list extensions;
+`,
+	Run: runListCmd,
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listIndexes represents the indexes command
+var listIndexes = &cobra.Command{
+	Use:   "indexes",
+	Short: parser.ShortDesc("list indexes"),
+	Long: `Usage: list indexes on <schema_name.table_or_view_name>[,<schema_name.table_or_view_name>...];
+
+Examples:
+  list indexes on foo_schema.bar_table
+  list indexes on foo_schema.bar_table,foo_schema.baz_table
+`,
+	Run: runListCmd,
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listRoles represents the roles command
+var listRoles = &cobra.Command{
+	Use:   "roles",
+	Short: parser.ShortDesc("list roles"),
+	Long: `Usage: list roles;
+`,
+	Run: runListCmd,
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// listTriggers represents the triggers command
+var listTriggers = &cobra.Command{
+	Use:   "triggers",
+	Short: parser.ShortDesc("list triggers"),
+	Long: `Usage: list triggers on <schema_name.table_name>[,<schema_name.table_name>...];
+
+Examples:
+  list triggers on foo_schema.bar_table
+  list triggers on foo_schema.bar_table,foo_schema.baz_table
+`,
+	Run: runListCmd,
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/nrfta/ddsl/log"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: parser.ShortDesc("migrate"),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("additional arguments required, use -h for help")
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateTopCmd)
+	migrateCmd.AddCommand(migrateBottomCmd)
+	migrateCmd.AddCommand(migrateToCmd)
+	migrateCmd.AddCommand(migrateRedoCmd)
+	migrateCmd.AddCommand(migrateBaselineCmd)
+	migrateCmd.AddCommand(migrateNewCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateVerifyCmd)
+
+	viper.BindEnv("ignore_checksums")
+
+	migrateCmd.PersistentFlags().Bool("ignore-checksums", false, "migrate even if applied migrations have changed (default DDSL_IGNORE_CHECKSUMS)")
+	viper.BindPFlag("ignore_checksums", migrateCmd.PersistentFlags().Lookup("ignore-checksums"))
+
+	viper.BindEnv("out_of_order")
+
+	migrateCmd.PersistentFlags().String("out-of-order", "reject", "policy for pending migrations older than the current version (default DDSL_OUT_OF_ORDER=reject). May be reject, warn, or apply.")
+	viper.BindPFlag("out_of_order", migrateCmd.PersistentFlags().Lookup("out-of-order"))
+
+	viper.BindEnv("migration_version_scheme")
+
+	migrateNewCmd.Flags().String("version-scheme", "timestamp", "version scheme for new migrations (default DDSL_MIGRATION_VERSION_SCHEME=timestamp). May be timestamp or sequential.")
+	viper.BindPFlag("migration_version_scheme", migrateNewCmd.Flags().Lookup("version-scheme"))
+}
+
+func runMigrateCmd(cmd *cobra.Command, args []string) {
+	command := fmt.Sprintf("migrate %s", cmd.Use)
+	if len(args) > 0 {
+		command += " "
+	}
+	command += strings.Join(args, " ")
+
+	code, err := runCLICommand(command)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateBaselineCmd represents the migrate baseline command
+var migrateBaselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: parser.ShortDesc("migrate baseline"),
+	Long: `Usage: migrate baseline <version>;
+
+Records every pending migration up to and including the given version as
+applied without running it. Use this once when adopting migrations for a
+database that already has the schema those migrations would create.
+
+Examples:
+  migrate baseline 20200101120000
+  migrate baseline 20200101120000 --dry-run
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateBottomCmd represents the migrate bottom command
+var migrateBottomCmd = &cobra.Command{
+	Use:   "bottom",
+	Short: parser.ShortDesc("migrate bottom"),
+	Long: `Usage: migrate bottom;
+
+Reverts all applied migrations in reverse version order.
+`,
+	Run: runMigrateCmd,
+}
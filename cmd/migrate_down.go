@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateDownCmd represents the migrate down command
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: parser.ShortDesc("migrate down"),
+	Long: `Usage: migrate down [<number_of_versions>];
+
+Reverts the most recently applied migrations in reverse version order.
+One migration is reverted when the number of versions is omitted.
+
+Examples:
+  migrate down
+  migrate down 2
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateNewCmd represents the migrate new command
+var migrateNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: parser.ShortDesc("migrate new"),
+	Long: `Usage: migrate new <title>;
+
+Creates <version>_<title>.up.ddsl and <version>_<title>.down.ddsl in the
+migrations directory of the source repo, which must be a file:// URL. The
+version is a UTC timestamp (YYYYMMDDHHMMSS) or, with the sequential scheme,
+one more than the latest migration.
+
+Examples:
+  migrate new add_users_table
+  migrate new add_users_table --version-scheme sequential
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateRedoCmd represents the migrate redo command
+var migrateRedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: parser.ShortDesc("migrate redo"),
+	Long: `Usage: migrate redo [<number_of_versions>];
+
+Reverts the most recently applied migrations and then applies them again.
+One migration is redone when the number of versions is omitted.
+
+Examples:
+  migrate redo
+  migrate redo 2
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateStatusCmd represents the migrate status command
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: parser.ShortDesc("migrate status"),
+	Long: `Usage: migrate status;
+
+Lists every migration found in the source repo or recorded in the ddsl_migrations
+table with its status: applied, pending, dirty or missing (applied but its files
+are no longer in the source repo).
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateToCmd represents the migrate to command
+var migrateToCmd = &cobra.Command{
+	Use:   "to",
+	Short: parser.ShortDesc("migrate to"),
+	Long: `Usage: migrate to <version>;
+
+Migrates the database up or down to the given version. Applied migrations
+above the version are reverted and pending migrations up to and including
+it are applied. Version 0 reverts all migrations.
+
+Examples:
+  migrate to 20200101120000
+  migrate to 0
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateTopCmd represents the migrate top command
+var migrateTopCmd = &cobra.Command{
+	Use:   "top",
+	Short: parser.ShortDesc("migrate top"),
+	Long: `Usage: migrate top;
+
+Applies all pending migrations in version order.
+`,
+	Run: runMigrateCmd,
+}
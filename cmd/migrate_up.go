@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateUpCmd represents the migrate up command
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: parser.ShortDesc("migrate up"),
+	Long: `Usage: migrate up [<number_of_versions>];
+
+Applies the next pending migrations in version order. One migration is
+applied when the number of versions is omitted.
+
+Examples:
+  migrate up
+  migrate up 2
+`,
+	Run: runMigrateCmd,
+}
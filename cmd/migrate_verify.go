@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/parser"
+	"github.com/spf13/cobra"
+)
+
+// migrateVerifyCmd represents the migrate verify command
+var migrateVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: parser.ShortDesc("migrate verify"),
+	Long: `Usage: migrate verify;
+
+Compares the checksum recorded for each applied migration with its up file in the
+source repo and lists the migrations that have changed since they were applied.
+Exits with a non-zero code when any have changed, so it may be used in CI.
+`,
+	Run: runMigrateCmd,
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/nrfta/ddsl/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "List the instructions a command would execute without executing them",
+	Long: `Usage: plan <command>
+
+Lists the instructions of a command in the order they would be executed: the
+DDSL commands, the files and scripts they run, their schema and item, the
+transaction around them and the nesting of DDSL files such as seeds and
+migrations. Nothing is executed. The list supports the same output formats as
+the list command, so "--format json" produces a document that can be attached
+to change requests or compared between releases. The --plan switch does the
+same for a ddsl file.
+
+Examples:
+  plan create tables in foo_schema
+  plan seed database
+  plan --format json migrate up
+  ddsl --plan -f /path/to/file.ddsl
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("plan", true)
+		code, err := runCLICommand(strings.Join(args, " "))
+		if err != nil {
+			log.Error(err.Error())
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+}
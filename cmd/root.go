@@ -81,7 +81,12 @@ func makeExecContext(autoTx bool) *exec.Context {
 		fmt.Println("no source repository provided")
 		os.Exit(1)
 	}
-	return exec.NewContext(src, db, autoTx, viper.GetBool("dry_run"), viper.GetString("format"))
+	ctx := exec.NewContext(src, db, autoTx, viper.GetBool("dry_run"), viper.GetString("format"))
+	ctx.IgnoreChecksums = viper.GetBool("ignore_checksums")
+	ctx.MigrationVersionScheme = viper.GetString("migration_version_scheme")
+	ctx.OutOfOrder = viper.GetString("out_of_order")
+	ctx.Plan = viper.GetBool("plan")
+	return ctx
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -109,6 +114,9 @@ func init() {
 	rootCmd.PersistentFlags().Bool("dry-run", false, "take no action but output what would be done")
 	viper.BindPFlag("dry_run", rootCmd.PersistentFlags().Lookup("dry-run"))
 
+	rootCmd.PersistentFlags().Bool("plan", false, "list the instructions a command or script would execute without executing them")
+	viper.BindPFlag("plan", rootCmd.PersistentFlags().Lookup("plan"))
+
 	rootCmd.Flags().BoolVar(&version, "version", false, "show version number and exit")
 	rootCmd.Flags().StringVarP(&file, "file", "f", "", "file containing DDSL commands")
 
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVReader reads CSV records the way COPY ... CSV does in Postgres: a field that is empty and
+// unquoted is NULL, while a quoted empty field ("") is an empty string. encoding/csv does not
+// report which fields were quoted, so it cannot keep the distinction. Drivers that parse seed
+// files use it so that the files load the same way on every database.
+type CSVReader struct {
+	r         *bufio.Reader
+	delimiter rune
+}
+
+// NewCSVReader returns a reader of CSV fields separated by the delimiter, or by commas if the
+// delimiter is empty
+func NewCSVReader(r io.Reader, delimiter string) (*CSVReader, error) {
+	comma := ','
+	if len(delimiter) > 0 {
+		d, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || d == '"' || d == '\r' || d == '\n' {
+			return nil, fmt.Errorf("delimiter must be a single character other than a quote or newline: %s", delimiter)
+		}
+		comma = d
+	}
+	return &CSVReader{r: bufio.NewReader(r), delimiter: comma}, nil
+}
+
+// Read returns the fields of the next record, nil standing for NULL. Blank lines are skipped.
+// A quote starts or ends a quoted section anywhere in a field, and two quotes in a quoted
+// section are a literal quote.
+func (c *CSVReader) Read() ([]interface{}, error) {
+	var (
+		record   []interface{}
+		field    strings.Builder
+		quoted   bool
+		inQuotes bool
+		empty    = true
+	)
+
+	endField := func() {
+		if field.Len() == 0 && !quoted {
+			record = append(record, nil)
+		} else {
+			record = append(record, field.String())
+		}
+		field.Reset()
+		quoted = false
+	}
+
+	for {
+		ch, _, err := c.r.ReadRune()
+		if err == io.EOF {
+			if inQuotes {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if empty {
+				return nil, io.EOF
+			}
+			endField()
+			return record, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case inQuotes && ch == '"':
+			next, _, err := c.r.ReadRune()
+			if err == nil && next == '"' {
+				field.WriteRune('"')
+				continue
+			}
+			if err == nil {
+				c.r.UnreadRune()
+			}
+			inQuotes = false
+		case inQuotes:
+			field.WriteRune(ch)
+		case ch == '"':
+			inQuotes, quoted, empty = true, true, false
+		case ch == c.delimiter:
+			endField()
+			empty = false
+		case ch == '\r' || ch == '\n':
+			if ch == '\r' {
+				if next, _, err := c.r.ReadRune(); err == nil && next != '\n' {
+					c.r.UnreadRune()
+				}
+			}
+			if empty {
+				continue
+			}
+			endField()
+			return record, nil
+		default:
+			field.WriteRune(ch)
+			empty = false
+		}
+	}
+}
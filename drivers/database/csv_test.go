@@ -0,0 +1,57 @@
+package database
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	testcases := []struct {
+		input     string
+		delimiter string
+		want      [][]interface{}
+	}{
+		{"1,\"\",\n", ",", [][]interface{}{{"1", "", nil}}},
+		{"a,\"b \"\"c\"\"\"\r\n\r\n\"d\ne\",f", ",", [][]interface{}{{"a", `b "c"`}, {"d\ne", "f"}}},
+		{"a;\"b;c\"d;;\n", ";", [][]interface{}{{"a", "b;cd", nil, nil}}},
+		{"", "", nil},
+	}
+	for i, tc := range testcases {
+		t.Run("tc"+strconv.Itoa(i), func(t *testing.T) {
+			r, err := NewCSVReader(strings.NewReader(tc.input), tc.delimiter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]interface{}
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %#v but got %#v", tc.want, got)
+			}
+		})
+	}
+
+	for _, delimiter := range []string{";;", "\"", "\n"} {
+		if _, err := NewCSVReader(strings.NewReader(""), delimiter); err == nil {
+			t.Fatalf("expected error for delimiter %q", delimiter)
+		}
+	}
+
+	r, err := NewCSVReader(strings.NewReader("\"a"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %v for an unterminated quote but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
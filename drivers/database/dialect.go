@@ -0,0 +1,336 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListSeparator separates the elements of list columns returned by dialect queries
+const ListSeparator = "\x1f"
+
+// Dialect is the SQL of a database system. Each driver supplies its own so that ddsl's
+// bookkeeping statements and the introspection queries run on any backend. Queries take their
+// arguments as bound parameters.
+type Dialect struct {
+	// Placeholder returns the bound parameter placeholder for the nth argument of a statement,
+	// counting from 1.
+	Placeholder func(n int) string
+
+	// SchemasQuery returns the names of the user schemas.
+	SchemasQuery string
+
+	// TableExistsQuery takes a table name and returns the number of tables of that name that an
+	// unqualified statement could refer to, for ddsl's own tables.
+	TableExistsQuery string
+
+	// TablesQuery, ViewsQuery, FunctionsQuery, ProceduresQuery and TypesQuery take the schema name
+	// and return item_type, schema_name and item_name. An empty query returns no items.
+	TablesQuery     string
+	ViewsQuery      string
+	FunctionsQuery  string
+	ProceduresQuery string
+	TypesQuery      string
+
+	// ForeignKeysQuery takes the schema name and returns the fields of ForeignKeyInfo in order.
+	ForeignKeysQuery string
+
+	// ExtensionsQuery returns the fields of ExtensionInfo in order. An empty query returns no
+	// extensions.
+	ExtensionsQuery string
+
+	// RolesQuery returns the fields of RoleInfo in order, MemberOf being a list. An empty query
+	// returns no roles.
+	RolesQuery string
+
+	// ColumnsQuery, IndexesQuery, ConstraintsQuery and TriggersQuery take the schema and table names
+	// and return the fields of ColumnInfo, IndexInfo, ConstraintInfo and TriggerInfo in order,
+	// IndexInfo.Columns and TriggerInfo.Events being lists. An empty query returns no items.
+	ColumnsQuery     string
+	IndexesQuery     string
+	ConstraintsQuery string
+	TriggersQuery    string
+
+	// DefinitionQueries are the queries of Definitions by definition type. They take the schema and
+	// object names and return the Create and Drop statements of DefinitionInfo. A missing or empty
+	// query returns no definitions.
+	DefinitionQueries map[string]string
+}
+
+// QuestionMarkPlaceholder is the placeholder of most database systems
+func QuestionMarkPlaceholder(n int) string {
+	return "?"
+}
+
+// DollarPlaceholder is the numbered placeholder of Postgres
+func DollarPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Bind replaces the ? placeholders of a statement with the dialect's placeholders. The statement
+// must not contain a ? other than its placeholders.
+func (d *Dialect) Bind(query string) string {
+	parts := strings.Split(query, "?")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for i, part := range parts[1:] {
+		b.WriteString(d.Placeholder(i + 1))
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// QueryStrings runs a query returning a single column of strings
+func QueryStrings(d Driver, query string, params ...interface{}) ([]string, error) {
+	rows, err := d.Query(strings.NewReader(query), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		s := ""
+		if err = rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+// QuerySchemaItems runs a schema item query of a dialect for the schema
+func QuerySchemaItems(d Driver, query string, schema string) ([]*SchemaItemInfo, error) {
+	schemaItems := []*SchemaItemInfo{}
+	if len(query) == 0 {
+		return schemaItems, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &SchemaItemInfo{}
+		if err = rows.Scan(&item.ItemType, &item.SchemaName, &item.ItemName); err != nil {
+			return nil, err
+		}
+		schemaItems = append(schemaItems, item)
+	}
+
+	return schemaItems, rows.Err()
+}
+
+// QueryForeignKeys runs the foreign keys query of a dialect for the schema
+func QueryForeignKeys(d Driver, query string, schema string) ([]*ForeignKeyInfo, error) {
+	rows, err := d.Query(strings.NewReader(query), schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fkInfo := []*ForeignKeyInfo{}
+	for rows.Next() {
+		item := &ForeignKeyInfo{}
+		err = rows.Scan(&item.ParentSchemaName, &item.ParentTableName, &item.ParentColumnName,
+			&item.ChildSchemaName, &item.ChildTableName, &item.ChildColumnName)
+		if err != nil {
+			return nil, err
+		}
+		fkInfo = append(fkInfo, item)
+	}
+
+	return fkInfo, rows.Err()
+}
+
+// QueryExtensions runs the extensions query of a dialect
+func QueryExtensions(d Driver, query string) ([]*ExtensionInfo, error) {
+	extensions := []*ExtensionInfo{}
+	if len(query) == 0 {
+		return extensions, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &ExtensionInfo{}
+		if err = rows.Scan(&item.Name, &item.Version, &item.SchemaName); err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, item)
+	}
+
+	return extensions, rows.Err()
+}
+
+// QueryRoles runs the roles query of a dialect
+func QueryRoles(d Driver, query string) ([]*RoleInfo, error) {
+	roles := []*RoleInfo{}
+	if len(query) == 0 {
+		return roles, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &RoleInfo{}
+		memberOf := ""
+		err = rows.Scan(&item.Name, &item.Login, &item.Superuser, &item.CreateDB, &item.CreateRole,
+			&item.Inherit, &item.Replication, &memberOf)
+		if err != nil {
+			return nil, err
+		}
+		item.MemberOf = splitList(memberOf)
+		roles = append(roles, item)
+	}
+
+	return roles, rows.Err()
+}
+
+// QueryColumns runs the columns query of a dialect for the table
+func QueryColumns(d Driver, query string, schema, table string) ([]*ColumnInfo, error) {
+	columns := []*ColumnInfo{}
+	if len(query) == 0 {
+		return columns, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &ColumnInfo{}
+		err = rows.Scan(&item.SchemaName, &item.TableName, &item.ColumnName, &item.Ordinal, &item.DataType,
+			&item.Nullable, &item.Default, &item.Identity, &item.Generated, &item.Comment)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, item)
+	}
+
+	return columns, rows.Err()
+}
+
+// QueryIndexes runs the indexes query of a dialect for the table
+func QueryIndexes(d Driver, query string, schema, table string) ([]*IndexInfo, error) {
+	indexes := []*IndexInfo{}
+	if len(query) == 0 {
+		return indexes, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &IndexInfo{}
+		columns := ""
+		err = rows.Scan(&item.SchemaName, &item.TableName, &item.IndexName, &columns, &item.Unique,
+			&item.Method, &item.Predicate)
+		if err != nil {
+			return nil, err
+		}
+		item.Columns = splitList(columns)
+		indexes = append(indexes, item)
+	}
+
+	return indexes, rows.Err()
+}
+
+// QueryConstraints runs the constraints query of a dialect for the table
+func QueryConstraints(d Driver, query string, schema, table string) ([]*ConstraintInfo, error) {
+	constraints := []*ConstraintInfo{}
+	if len(query) == 0 {
+		return constraints, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &ConstraintInfo{}
+		err = rows.Scan(&item.SchemaName, &item.TableName, &item.ConstraintName, &item.ConstraintType, &item.Definition)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, item)
+	}
+
+	return constraints, rows.Err()
+}
+
+// QueryTriggers runs the triggers query of a dialect for the table
+func QueryTriggers(d Driver, query string, schema, table string) ([]*TriggerInfo, error) {
+	triggers := []*TriggerInfo{}
+	if len(query) == 0 {
+		return triggers, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &TriggerInfo{}
+		events := ""
+		err = rows.Scan(&item.SchemaName, &item.TableName, &item.TriggerName, &item.Timing, &events, &item.Function)
+		if err != nil {
+			return nil, err
+		}
+		item.Events = splitList(events)
+		triggers = append(triggers, item)
+	}
+
+	return triggers, rows.Err()
+}
+
+// QueryDefinitions runs a definition query of a dialect for the object
+func QueryDefinitions(d Driver, query string, schema, name string) ([]*DefinitionInfo, error) {
+	definitions := []*DefinitionInfo{}
+	if len(query) == 0 {
+		return definitions, nil
+	}
+
+	rows, err := d.Query(strings.NewReader(query), schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &DefinitionInfo{}
+		if err = rows.Scan(&item.Create, &item.Drop); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, item)
+	}
+
+	return definitions, rows.Err()
+}
+
+// splitList splits a list column returned by a dialect query
+func splitList(list string) []string {
+	if len(list) == 0 {
+		return []string{}
+	}
+	return strings.Split(list, ListSeparator)
+}
@@ -0,0 +1,20 @@
+package database
+
+import "testing"
+
+func TestBind(t *testing.T) {
+	for _, tc := range []struct {
+		placeholder func(int) string
+		query       string
+		expected    string
+	}{
+		{QuestionMarkPlaceholder, "UPDATE t SET a = ? WHERE b = ?", "UPDATE t SET a = ? WHERE b = ?"},
+		{DollarPlaceholder, "UPDATE t SET a = ? WHERE b = ?", "UPDATE t SET a = $1 WHERE b = $2"},
+		{DollarPlaceholder, "SELECT 1", "SELECT 1"},
+	} {
+		d := &Dialect{Placeholder: tc.placeholder}
+		if actual := d.Bind(tc.query); actual != tc.expected {
+			t.Fatalf("expected %q, got %q", tc.expected, actual)
+		}
+	}
+}
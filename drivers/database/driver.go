@@ -18,45 +18,17 @@ const (
 	SchemaItemTypeFunction  = "FUNCTION"
 	SchemaItemTypeProcedure = "PROCEDURE"
 	SchemaItemTypeType      = "TYPE"
-	SQLQuerySchemas         = "SELECT schema_name FROM information_schema.schemata;"
-	SQLQueryTables          = `
-		SELECT 'TABLE' AS item_type, table_schema AS schema_name, table_name AS item_name FROM information_schema.tables 
-		WHERE table_schema = '%s' AND table_type IN ('TABLE', 'BASE TABLE')
-	`
-	SQLQueryViews = `
-		SELECT 'VIEW' AS item_type, table_schema AS schema_name, table_name AS item_name FROM information_schema.tables 
-		WHERE table_schema = '%s' AND table_type = 'VIEW'
-	`
-	SQLQueryFunctions = `
-		SELECT 'FUNCTION' AS item_type, specific_schema AS schema_name, routine_name AS item_name 
-		FROM information_schema.routines
-		WHERE specific_schema = '%s' AND routine_type = 'FUNCTION'
-	`
-	SQLQueryProcedures = `
-		SELECT 'PROCEDURE' AS item_type, specific_schema AS schema_name, routine_name AS item_name 
-		FROM information_schema.routines
-		WHERE specific_schema = '%s' AND routine_type = 'PROCEDURE'
-	`
-	SQLQueryTypes = `
-		SELECT 'TYPE' AS item_type, user_defined_type_schema AS schema_name, user_defined_type_name AS item_name 
-		FROM information_schema.user_defined_types
-		WHERE user_defined_type_schema = '%s'
-	`
-	SQLQueryForeignKeys = `
-		SELECT
-			tc.table_schema AS parent_schema_name, tc.table_name AS parent_item_name, 
-			kcu.column_name AS parent_column_name,
-			ccu.table_schema AS child_schema_name, ccu.table_name AS child_item_name,
-			ccu.column_name AS child_column_name
-		FROM
-			information_schema.table_constraints AS tc
-			JOIN information_schema.key_column_usage 
-				AS kcu ON tc.constraint_name = kcu.constraint_name
-			JOIN information_schema.constraint_column_usage 
-				AS ccu ON ccu.constraint_name = tc.constraint_name
-		WHERE constraint_type = 'FOREIGN KEY'
-          AND tc.table_schema = '%s';
-	`
+)
+
+// Definition types of Definitions besides the schema item types
+const (
+	DefinitionTypeSchema            = "SCHEMA"
+	DefinitionTypeIndexes           = "INDEXES"
+	DefinitionTypeConstraints       = "CONSTRAINTS"
+	DefinitionTypeForeignKeys       = "FOREIGN KEYS"
+	DefinitionTypeTriggers          = "TRIGGERS"
+	DefinitionTypeTablePrivileges   = "TABLE PRIVILEGES"
+	DefinitionTypeRoutinePrivileges = "ROUTINE PRIVILEGES"
 )
 
 var (
@@ -64,6 +36,10 @@ var (
 	// when requested.
 	ErrLocked = fmt.Errorf("can't acquire lock")
 
+	// ErrDatabaseDirty should be returned when a migration failed part way
+	// and the database must be repaired before migrating further.
+	ErrDatabaseDirty = fmt.Errorf("database is dirty")
+
 	driversMu sync.RWMutex
 	drivers   = make(map[string]Driver)
 )
@@ -124,8 +100,8 @@ type Driver interface {
 	// Query should query the database and return results
 	Query(command io.Reader, params ...interface{}) (*sql.Rows, error)
 
-	// ImportCSV imports a csv file into the database.
-	ImportCSV(filePath, schemaName, tableName, delimiter string, header bool) (output string, err error)
+	// ImportCSV imports csv content into the database.
+	ImportCSV(csv io.Reader, schemaName, tableName, delimiter string, header bool) (output string, err error)
 
 	// User returns the database user.
 	User() string
@@ -154,14 +130,34 @@ type Driver interface {
 	// SchemaItems returns all items in the database schema
 	SchemaItems(schema string) ([]*SchemaItemInfo, error)
 
-	// Extensions returns the names of the database extensions
-	Extensions() ([]string, error)
+	// Extensions returns the extensions installed in the database
+	Extensions() ([]*ExtensionInfo, error)
 
 	// ForeignKeys returns the names of the foreign keys
 	ForeignKeys(schema string) ([]*ForeignKeyInfo, error)
 
-	// Roles returns the names of the database roles
-	Roles() ([]string, error)
+	// Roles returns the database roles
+	Roles() ([]*RoleInfo, error)
+
+	// Columns returns the columns of the table or view in order
+	Columns(schema, table string) ([]*ColumnInfo, error)
+
+	// Indexes returns the indexes on the table or view
+	Indexes(schema, table string) ([]*IndexInfo, error)
+
+	// Constraints returns the constraints on the table
+	Constraints(schema, table string) ([]*ConstraintInfo, error)
+
+	// Triggers returns the triggers on the table
+	Triggers(schema, table string) ([]*TriggerInfo, error)
+
+	// Definitions returns the statements creating and dropping an object of the definition type: a
+	// schema, whose name is also passed as the object name, a schema item, or the indexes,
+	// constraints, foreign keys, triggers or privileges of a table, view, function or procedure.
+	Definitions(definitionType, schema, name string) ([]*DefinitionInfo, error)
+
+	// Dialect returns the SQL dialect of the database.
+	Dialect() *Dialect
 }
 
 type SchemaItemInfo struct {
@@ -184,6 +180,96 @@ type ForeignKeyInfo struct {
 	ChildColumnName  string
 }
 
+type ExtensionInfo struct {
+	Name       string
+	Version    string
+	SchemaName string
+}
+
+type RoleInfo struct {
+	Name        string
+	Login       bool
+	Superuser   bool
+	CreateDB    bool
+	CreateRole  bool
+	Inherit     bool
+	Replication bool
+
+	// MemberOf is the names of the roles the role is a member of
+	MemberOf []string
+}
+
+type ColumnInfo struct {
+	SchemaName string
+	TableName  string
+	ColumnName string
+
+	// Ordinal is the position of the column in the table, counting from 1
+	Ordinal  int
+	DataType string
+	Nullable bool
+
+	// Default is the default expression of the column
+	Default string
+
+	// Identity is how an identity column generates its values, e.g. BY DEFAULT or AUTO_INCREMENT
+	Identity string
+
+	// Generated is the generation expression of a generated column, where known, followed by
+	// STORED or VIRTUAL
+	Generated string
+	Comment   string
+}
+
+type IndexInfo struct {
+	SchemaName string
+	TableName  string
+	IndexName  string
+
+	// Columns is the indexed columns or expressions in order
+	Columns []string
+	Unique  bool
+
+	// Method is the index access method, e.g. btree
+	Method string
+
+	// Predicate is the WHERE clause of a partial index
+	Predicate string
+}
+
+type ConstraintInfo struct {
+	SchemaName     string
+	TableName      string
+	ConstraintName string
+
+	// ConstraintType is one of PRIMARY KEY, UNIQUE, FOREIGN KEY, CHECK or EXCLUDE
+	ConstraintType string
+	Definition     string
+}
+
+type TriggerInfo struct {
+	SchemaName  string
+	TableName   string
+	TriggerName string
+
+	// Timing is one of BEFORE, AFTER or INSTEAD OF
+	Timing string
+
+	// Events is the events firing the trigger, e.g. INSERT and UPDATE
+	Events []string
+
+	// Function is the function the trigger calls, or its statements where triggers have no functions
+	Function string
+}
+
+type DefinitionInfo struct {
+	// Create is the statement creating the object, or granting privileges on it
+	Create string
+
+	// Drop is the statement dropping the object, or revoking the privileges
+	Drop string
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	u, err := nurl.Parse(url)
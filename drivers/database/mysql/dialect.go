@@ -0,0 +1,134 @@
+package mysql
+
+import "github.com/nrfta/ddsl/drivers/database"
+
+var dialect = &database.Dialect{
+	Placeholder: database.QuestionMarkPlaceholder,
+	SchemasQuery: `
+		SELECT schema_name FROM information_schema.schemata
+		WHERE schema_name NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+		ORDER BY schema_name
+	`,
+	TableExistsQuery: `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = ? AND table_type = 'BASE TABLE'
+	`,
+	TablesQuery: `
+		SELECT 'TABLE' AS item_type, table_schema AS schema_name, table_name AS item_name
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_type = 'BASE TABLE'
+		ORDER BY table_name
+	`,
+	ViewsQuery: `
+		SELECT 'VIEW' AS item_type, table_schema AS schema_name, table_name AS item_name
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_type = 'VIEW'
+		ORDER BY table_name
+	`,
+	FunctionsQuery: `
+		SELECT 'FUNCTION' AS item_type, routine_schema AS schema_name, routine_name AS item_name
+		FROM information_schema.routines
+		WHERE routine_schema = ? AND routine_type = 'FUNCTION'
+		ORDER BY routine_name
+	`,
+	ProceduresQuery: `
+		SELECT 'PROCEDURE' AS item_type, routine_schema AS schema_name, routine_name AS item_name
+		FROM information_schema.routines
+		WHERE routine_schema = ? AND routine_type = 'PROCEDURE'
+		ORDER BY routine_name
+	`,
+	// MySQL has no user defined types
+	TypesQuery: "",
+	ForeignKeysQuery: `
+		SELECT table_schema AS parent_schema_name, table_name AS parent_item_name,
+			column_name AS parent_column_name,
+			referenced_table_schema AS child_schema_name, referenced_table_name AS child_item_name,
+			referenced_column_name AS child_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = ? AND referenced_table_name IS NOT NULL
+		ORDER BY table_name, constraint_name, ordinal_position
+	`,
+	ColumnsQuery: `
+		SELECT table_schema, table_name, column_name, ordinal_position, column_type,
+			is_nullable = 'YES' AS nullable,
+			COALESCE(column_default, '') AS column_default,
+			IF(extra LIKE '%auto_increment%', 'AUTO_INCREMENT', '') AS identity,
+			IF(COALESCE(generation_expression, '') <> '',
+				CONCAT('(', generation_expression, ') ', IF(extra LIKE '%STORED%', 'STORED', 'VIRTUAL')), '') AS generated,
+			column_comment
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position
+	`,
+	IndexesQuery: `
+		SELECT table_schema, table_name, index_name,
+			GROUP_CONCAT(COALESCE(column_name, '') ORDER BY seq_in_index SEPARATOR '` + database.ListSeparator + `') AS columns,
+			MIN(non_unique) = 0 AS is_unique, index_type, '' AS predicate
+		FROM information_schema.statistics
+		WHERE table_schema = ? AND table_name = ?
+		GROUP BY table_schema, table_name, index_name, index_type
+		ORDER BY index_name
+	`,
+	// key_column_usage has no rows for CHECK constraints, whose definition is left as their type
+	ConstraintsQuery: `
+		SELECT tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type,
+			COALESCE(CONCAT(
+				tc.constraint_type, ' (',
+				GROUP_CONCAT(kcu.column_name ORDER BY kcu.ordinal_position SEPARATOR ', '), ')',
+				IF(tc.constraint_type = 'FOREIGN KEY', CONCAT(
+					' REFERENCES ', MIN(kcu.referenced_table_schema), '.', MIN(kcu.referenced_table_name), ' (',
+					GROUP_CONCAT(kcu.referenced_column_name ORDER BY kcu.ordinal_position SEPARATOR ', '), ')'
+				), '')
+			), tc.constraint_type) AS definition
+		FROM information_schema.table_constraints AS tc
+			LEFT JOIN information_schema.key_column_usage AS kcu
+				ON kcu.constraint_schema = tc.constraint_schema
+				AND kcu.table_name = tc.table_name
+				AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = ? AND tc.table_name = ?
+		GROUP BY tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type
+		ORDER BY tc.constraint_name
+	`,
+	// MySQL triggers fire on a single event and have statements rather than functions
+	TriggersQuery: `
+		SELECT event_object_schema, event_object_table, trigger_name, action_timing, event_manipulation,
+			action_statement
+		FROM information_schema.triggers
+		WHERE event_object_schema = ? AND event_object_table = ?
+		ORDER BY trigger_name
+	`,
+}
+
+// viewDefinitionQuery returns the definition of a view
+const viewDefinitionQuery = `
+	SELECT view_definition FROM information_schema.views
+	WHERE table_schema = ? AND table_name = ?
+`
+
+// foreignKeyDefinitionsQuery returns the name, columns, referenced schema, table and columns, and
+// rules of the foreign keys of a table
+const foreignKeyDefinitionsQuery = `
+	SELECT kcu.constraint_name,
+		GROUP_CONCAT(kcu.column_name ORDER BY kcu.ordinal_position SEPARATOR '` + database.ListSeparator + `'),
+		MIN(kcu.referenced_table_schema), MIN(kcu.referenced_table_name),
+		GROUP_CONCAT(kcu.referenced_column_name ORDER BY kcu.ordinal_position SEPARATOR '` + database.ListSeparator + `'),
+		MIN(rc.update_rule), MIN(rc.delete_rule)
+	FROM information_schema.key_column_usage AS kcu
+		JOIN information_schema.referential_constraints AS rc
+			ON rc.constraint_schema = kcu.constraint_schema
+			AND rc.table_name = kcu.table_name
+			AND rc.constraint_name = kcu.constraint_name
+	WHERE kcu.table_schema = ? AND kcu.table_name = ? AND kcu.referenced_table_name IS NOT NULL
+	GROUP BY kcu.constraint_name
+	ORDER BY kcu.constraint_name
+`
+
+// tablePrivilegesQuery returns the grantee, grant option and privileges granted on a table or view
+const tablePrivilegesQuery = `
+	SELECT grantee, is_grantable = 'YES',
+		GROUP_CONCAT(privilege_type ORDER BY privilege_type SEPARATOR ', ')
+	FROM information_schema.table_privileges
+	WHERE table_schema = ? AND table_name = ?
+	GROUP BY grantee, is_grantable
+	ORDER BY grantee, is_grantable
+`
@@ -0,0 +1,367 @@
+package postgres
+
+import "github.com/nrfta/ddsl/drivers/database"
+
+// dialect holds the queries that run on every supported server version
+var dialect = &database.Dialect{
+	Placeholder: database.DollarPlaceholder,
+	SchemasQuery: `
+		SELECT schema_name FROM information_schema.schemata
+		WHERE schema_name NOT IN ('pg_catalog', 'information_schema') AND schema_name NOT LIKE 'pg_toast%'
+		ORDER BY schema_name
+	`,
+	TableExistsQuery: `
+		SELECT COUNT(*)
+		FROM pg_class AS c
+			JOIN pg_namespace AS n ON n.oid = c.relnamespace
+		WHERE c.relname = $1 AND c.relkind IN ('r', 'p') AND n.nspname = ANY(current_schemas(false))
+	`,
+	TablesQuery: `
+		SELECT 'TABLE' AS item_type, table_schema AS schema_name, table_name AS item_name
+		FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type IN ('TABLE', 'BASE TABLE')
+		ORDER BY table_name
+	`,
+	ViewsQuery: `
+		SELECT 'VIEW' AS item_type, table_schema AS schema_name, table_name AS item_name
+		FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type = 'VIEW'
+		ORDER BY table_name
+	`,
+	FunctionsQuery: `
+		SELECT 'FUNCTION' AS item_type, specific_schema AS schema_name, routine_name AS item_name
+		FROM information_schema.routines
+		WHERE specific_schema = $1 AND routine_type = 'FUNCTION'
+		ORDER BY routine_name
+	`,
+	ProceduresQuery: `
+		SELECT 'PROCEDURE' AS item_type, specific_schema AS schema_name, routine_name AS item_name
+		FROM information_schema.routines
+		WHERE specific_schema = $1 AND routine_type = 'PROCEDURE'
+		ORDER BY routine_name
+	`,
+	TypesQuery: `
+		SELECT 'TYPE' AS item_type, user_defined_type_schema AS schema_name, user_defined_type_name AS item_name
+		FROM information_schema.user_defined_types
+		WHERE user_defined_type_schema = $1
+		ORDER BY user_defined_type_name
+	`,
+	ForeignKeysQuery: `
+		SELECT
+			tc.table_schema AS parent_schema_name, tc.table_name AS parent_item_name,
+			kcu.column_name AS parent_column_name,
+			ccu.table_schema AS child_schema_name, ccu.table_name AS child_item_name,
+			ccu.column_name AS child_column_name
+		FROM
+			information_schema.table_constraints AS tc
+			JOIN information_schema.key_column_usage
+				AS kcu ON tc.constraint_name = kcu.constraint_name
+			JOIN information_schema.constraint_column_usage
+				AS ccu ON ccu.constraint_name = tc.constraint_name
+		WHERE constraint_type = 'FOREIGN KEY'
+			AND tc.table_schema = $1
+	`,
+	ExtensionsQuery: `
+		SELECT e.extname, e.extversion, n.nspname
+		FROM pg_extension AS e
+			JOIN pg_namespace AS n ON n.oid = e.extnamespace
+		ORDER BY e.extname
+	`,
+	RolesQuery: `
+		SELECT
+			r.rolname, r.rolcanlogin, r.rolsuper, r.rolcreatedb, r.rolcreaterole, r.rolinherit, r.rolreplication,
+			COALESCE(string_agg(m.rolname, chr(31) ORDER BY m.rolname), '') AS member_of
+		FROM pg_roles AS r
+			LEFT JOIN pg_auth_members AS am ON am.member = r.oid
+			LEFT JOIN pg_roles AS m ON m.oid = am.roleid
+		WHERE r.rolname NOT LIKE 'pg\_%'
+		GROUP BY r.rolname, r.rolcanlogin, r.rolsuper, r.rolcreatedb, r.rolcreaterole, r.rolinherit, r.rolreplication
+		ORDER BY r.rolname
+	`,
+	ColumnsQuery: `
+		SELECT
+			c.table_schema, c.table_name, c.column_name, c.ordinal_position::integer,
+			format_type(a.atttypid, a.atttypmod) AS data_type,
+			c.is_nullable = 'YES' AS nullable,
+			COALESCE(c.column_default, '') AS column_default,
+			CASE WHEN c.is_identity = 'YES' THEN c.identity_generation ELSE '' END AS identity,
+			CASE WHEN c.is_generated = 'ALWAYS' THEN '(' || c.generation_expression || ') STORED' ELSE '' END AS generated,
+			COALESCE(col_description(a.attrelid, a.attnum), '') AS comment
+		FROM information_schema.columns AS c
+			JOIN pg_namespace AS n ON n.nspname = c.table_schema
+			JOIN pg_class AS t ON t.relnamespace = n.oid AND t.relname = c.table_name
+			JOIN pg_attribute AS a ON a.attrelid = t.oid AND a.attname = c.column_name
+		WHERE c.table_schema = $1 AND c.table_name = $2
+		ORDER BY c.ordinal_position
+	`,
+	IndexesQuery: `
+		SELECT
+			n.nspname, t.relname, i.relname,
+			array_to_string(ARRAY(
+				SELECT pg_get_indexdef(ix.indexrelid, k + 1, true)
+				FROM generate_subscripts(ix.indkey, 1) AS k
+				ORDER BY k
+			), chr(31)) AS columns,
+			ix.indisunique, am.amname, COALESCE(pg_get_expr(ix.indpred, ix.indrelid, true), '') AS predicate
+		FROM pg_index AS ix
+			JOIN pg_class AS i ON i.oid = ix.indexrelid
+			JOIN pg_class AS t ON t.oid = ix.indrelid
+			JOIN pg_namespace AS n ON n.oid = t.relnamespace
+			JOIN pg_am AS am ON am.oid = i.relam
+		WHERE n.nspname = $1 AND t.relname = $2
+		ORDER BY i.relname
+	`,
+	ConstraintsQuery: `
+		SELECT
+			n.nspname, c.relname, con.conname,
+			CASE con.contype
+				WHEN 'p' THEN 'PRIMARY KEY'
+				WHEN 'u' THEN 'UNIQUE'
+				WHEN 'f' THEN 'FOREIGN KEY'
+				WHEN 'c' THEN 'CHECK'
+				WHEN 'x' THEN 'EXCLUDE'
+				ELSE con.contype::text
+			END AS constraint_type,
+			pg_get_constraintdef(con.oid, true) AS definition
+		FROM pg_constraint AS con
+			JOIN pg_class AS c ON c.oid = con.conrelid
+			JOIN pg_namespace AS n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2
+		ORDER BY con.conname
+	`,
+	// tgtype is a bit mask of ROW (1), BEFORE (2), INSERT (4), DELETE (8), UPDATE (16),
+	// TRUNCATE (32) and INSTEAD (64)
+	TriggersQuery: `
+		SELECT
+			n.nspname, c.relname, t.tgname,
+			CASE
+				WHEN t.tgtype::integer & 2 <> 0 THEN 'BEFORE'
+				WHEN t.tgtype::integer & 64 <> 0 THEN 'INSTEAD OF'
+				ELSE 'AFTER'
+			END AS timing,
+			array_to_string(ARRAY[
+				CASE WHEN t.tgtype::integer & 4 <> 0 THEN 'INSERT' END,
+				CASE WHEN t.tgtype::integer & 16 <> 0 THEN 'UPDATE' END,
+				CASE WHEN t.tgtype::integer & 8 <> 0 THEN 'DELETE' END,
+				CASE WHEN t.tgtype::integer & 32 <> 0 THEN 'TRUNCATE' END
+			], chr(31)) AS events,
+			pn.nspname || '.' || p.proname AS function
+		FROM pg_trigger AS t
+			JOIN pg_class AS c ON c.oid = t.tgrelid
+			JOIN pg_namespace AS n ON n.oid = c.relnamespace
+			JOIN pg_proc AS p ON p.oid = t.tgfoid
+			JOIN pg_namespace AS pn ON pn.oid = p.pronamespace
+		WHERE NOT t.tgisinternal AND n.nspname = $1 AND c.relname = $2
+		ORDER BY t.tgname
+	`,
+}
+
+// dialectFor returns the dialect of a server, whose definition queries use only the catalog
+// columns and syntax of its server_version_num
+func dialectFor(serverVersionNum int) *database.Dialect {
+	d := *dialect
+	d.DefinitionQueries = definitionQueries(serverVersionNum)
+	return &d
+}
+
+func definitionQueries(serverVersionNum int) map[string]string {
+	// identity columns are new in Postgres 10 and generated columns in 12
+	identity := "''"
+	if serverVersionNum >= 100000 {
+		identity = `CASE a.attidentity
+						WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+						WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+						ELSE ''
+					END`
+	}
+	generated := ""
+	if serverVersionNum >= 120000 {
+		generated = `
+						WHEN a.attgenerated = 's' THEN ' GENERATED ALWAYS AS (' || pg_get_expr(d.adbin, d.adrelid) || ') STORED'`
+	}
+
+	// procedures, prokind and ON ROUTINE are new in Postgres 11. Before, aggregates and window
+	// functions are flagged by proisagg and proiswindow.
+	functionKind, procedureKind, routine := "p.prokind = 'f'", "p.prokind = 'p'", "ROUTINE"
+	if serverVersionNum < 110000 {
+		functionKind, procedureKind, routine = "NOT p.proisagg AND NOT p.proiswindow", "FALSE", "FUNCTION"
+	}
+
+	return map[string]string{
+		database.DefinitionTypeSchema: `
+			SELECT 'CREATE SCHEMA ' || quote_ident(nspname) || ';', 'DROP SCHEMA ' || quote_ident(nspname) || ';'
+			FROM pg_namespace
+			WHERE nspname = $1 AND nspname = $2
+		`,
+		// the primary key is part of the table so that it exists before any foreign key referencing it
+		database.SchemaItemTypeTable: `
+			SELECT
+				'CREATE TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) || E' (\n' ||
+				string_agg(
+					'    ' || quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod) ||
+					` + identity + ` ||
+					CASE` + generated + `
+						WHEN d.adbin IS NOT NULL THEN ' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid)
+						ELSE ''
+					END ||
+					CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+					E',\n' ORDER BY a.attnum
+				) ||
+				COALESCE((
+					SELECT E',\n    CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid, true)
+					FROM pg_constraint AS con
+					WHERE con.conrelid = c.oid AND con.contype = 'p'
+				), '') || E'\n);',
+				'DROP TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) || ';'
+			FROM pg_class AS c
+				JOIN pg_namespace AS n ON n.oid = c.relnamespace
+				JOIN pg_attribute AS a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+				LEFT JOIN pg_attrdef AS d ON d.adrelid = c.oid AND d.adnum = a.attnum
+			WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind IN ('r', 'p')
+			GROUP BY n.nspname, c.relname, c.oid
+		`,
+		database.SchemaItemTypeView: `
+			SELECT
+				'CREATE VIEW ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) || E' AS\n' ||
+				pg_get_viewdef(c.oid, true),
+				'DROP VIEW ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) || ';'
+			FROM pg_class AS c
+				JOIN pg_namespace AS n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind = 'v'
+		`,
+		// overloaded functions have a definition each
+		database.SchemaItemTypeFunction: `
+			SELECT
+				rtrim(pg_get_functiondef(p.oid), E'\n') || ';',
+				'DROP FUNCTION ' || quote_ident(n.nspname) || '.' || quote_ident(p.proname) ||
+				'(' || pg_get_function_identity_arguments(p.oid) || ');'
+			FROM pg_proc AS p
+				JOIN pg_namespace AS n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2 AND ` + functionKind + `
+			ORDER BY p.oid
+		`,
+		database.SchemaItemTypeProcedure: `
+			SELECT
+				rtrim(pg_get_functiondef(p.oid), E'\n') || ';',
+				'DROP PROCEDURE ' || quote_ident(n.nspname) || '.' || quote_ident(p.proname) ||
+				'(' || pg_get_function_identity_arguments(p.oid) || ');'
+			FROM pg_proc AS p
+				JOIN pg_namespace AS n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2 AND ` + procedureKind + `
+			ORDER BY p.oid
+		`,
+		database.SchemaItemTypeType: `
+			SELECT
+				'CREATE TYPE ' || quote_ident(n.nspname) || '.' || quote_ident(t.typname) ||
+				CASE t.typtype
+					WHEN 'e' THEN ' AS ENUM (' || COALESCE((
+						SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder)
+						FROM pg_enum AS e
+						WHERE e.enumtypid = t.oid
+					), '') || ');'
+					ELSE E' AS (\n' || COALESCE((
+						SELECT string_agg('    ' || quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod),
+							E',\n' ORDER BY a.attnum)
+						FROM pg_attribute AS a
+						WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+					), '') || E'\n);'
+				END,
+				'DROP TYPE ' || quote_ident(n.nspname) || '.' || quote_ident(t.typname) || ';'
+			FROM pg_type AS t
+				JOIN pg_namespace AS n ON n.oid = t.typnamespace
+				LEFT JOIN pg_class AS c ON c.oid = t.typrelid
+			WHERE n.nspname = $1 AND t.typname = $2 AND (t.typtype = 'e' OR (t.typtype = 'c' AND c.relkind = 'c'))
+		`,
+		// indexes backing constraints are created with their constraints
+		database.DefinitionTypeIndexes: `
+			SELECT pg_get_indexdef(i.oid) || ';', 'DROP INDEX ' || quote_ident(n.nspname) || '.' || quote_ident(i.relname) || ';'
+			FROM pg_index AS ix
+				JOIN pg_class AS i ON i.oid = ix.indexrelid
+				JOIN pg_class AS t ON t.oid = ix.indrelid
+				JOIN pg_namespace AS n ON n.oid = t.relnamespace
+			WHERE n.nspname = $1 AND t.relname = $2
+				AND NOT EXISTS (
+					SELECT 1 FROM pg_constraint AS con
+					WHERE con.conindid = ix.indexrelid AND con.conrelid = ix.indrelid AND con.contype IN ('p', 'u', 'x')
+				)
+			ORDER BY i.relname
+		`,
+		database.DefinitionTypeConstraints: `
+			SELECT
+				'ALTER TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) ||
+				' ADD CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid, true) || ';',
+				'ALTER TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) ||
+				' DROP CONSTRAINT ' || quote_ident(con.conname) || ';'
+			FROM pg_constraint AS con
+				JOIN pg_class AS c ON c.oid = con.conrelid
+				JOIN pg_namespace AS n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2 AND con.contype IN ('u', 'c', 'x')
+			ORDER BY con.conname
+		`,
+		database.DefinitionTypeForeignKeys: `
+			SELECT
+				'ALTER TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) ||
+				' ADD CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid, true) || ';',
+				'ALTER TABLE ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) ||
+				' DROP CONSTRAINT ' || quote_ident(con.conname) || ';'
+			FROM pg_constraint AS con
+				JOIN pg_class AS c ON c.oid = con.conrelid
+				JOIN pg_namespace AS n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2 AND con.contype = 'f'
+			ORDER BY con.conname
+		`,
+		database.DefinitionTypeTriggers: `
+			SELECT
+				pg_get_triggerdef(t.oid, true) || ';',
+				'DROP TRIGGER ' || quote_ident(t.tgname) || ' ON ' || quote_ident(n.nspname) || '.' || quote_ident(c.relname) || ';'
+			FROM pg_trigger AS t
+				JOIN pg_class AS c ON c.oid = t.tgrelid
+				JOIN pg_namespace AS n ON n.oid = c.relnamespace
+			WHERE NOT t.tgisinternal AND n.nspname = $1 AND c.relname = $2
+			ORDER BY t.tgname
+		`,
+		// the privileges of the owner are implicit. Grantee 0 is PUBLIC.
+		database.DefinitionTypeTablePrivileges: `
+			SELECT
+				'GRANT ' || privileges || ' ON ' || object_name || ' TO ' || grantee ||
+				CASE WHEN is_grantable THEN ' WITH GRANT OPTION' ELSE '' END || ';',
+				'REVOKE ' || privileges || ' ON ' || object_name || ' FROM ' || grantee || ';'
+			FROM (
+				SELECT
+					quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS object_name,
+					CASE WHEN acl.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(r.rolname) END AS grantee,
+					acl.is_grantable,
+					string_agg(acl.privilege_type, ', ' ORDER BY acl.privilege_type) AS privileges
+				FROM pg_class AS c
+					JOIN pg_namespace AS n ON n.oid = c.relnamespace
+					CROSS JOIN LATERAL aclexplode(c.relacl) AS acl
+					LEFT JOIN pg_roles AS r ON r.oid = acl.grantee
+				WHERE n.nspname = $1 AND c.relname = $2 AND acl.grantee <> c.relowner
+				GROUP BY n.nspname, c.relname, acl.grantee, r.rolname, acl.is_grantable
+			) AS grants
+			ORDER BY grantee, is_grantable
+		`,
+		// functions and procedures without privileges granted or revoked are executable by PUBLIC
+		database.DefinitionTypeRoutinePrivileges: `
+			SELECT
+				'GRANT ' || privileges || ' ON ` + routine + ` ' || object_name || ' TO ' || grantee ||
+				CASE WHEN is_grantable THEN ' WITH GRANT OPTION' ELSE '' END || ';',
+				'REVOKE ' || privileges || ' ON ` + routine + ` ' || object_name || ' FROM ' || grantee || ';'
+			FROM (
+				SELECT
+					quote_ident(n.nspname) || '.' || quote_ident(p.proname) ||
+					'(' || pg_get_function_identity_arguments(p.oid) || ')' AS object_name,
+					CASE WHEN acl.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(r.rolname) END AS grantee,
+					acl.is_grantable,
+					string_agg(acl.privilege_type, ', ' ORDER BY acl.privilege_type) AS privileges
+				FROM pg_proc AS p
+					JOIN pg_namespace AS n ON n.oid = p.pronamespace
+					CROSS JOIN LATERAL aclexplode(p.proacl) AS acl
+					LEFT JOIN pg_roles AS r ON r.oid = acl.grantee
+				WHERE n.nspname = $1 AND p.proname = $2 AND acl.grantee <> p.proowner
+				GROUP BY n.nspname, p.proname, p.oid, acl.grantee, r.rolname, acl.is_grantable
+			) AS grants
+			ORDER BY object_name, grantee, is_grantable
+		`,
+	}
+}
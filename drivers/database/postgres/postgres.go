@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/golang-migrate/migrate"
 	"github.com/nrfta/ddsl/drivers/database"
-	"github.com/nrfta/ddsl/util"
 	"io"
 	"io/ioutil"
 	nurl "net/url"
@@ -27,7 +26,7 @@ var (
 	ErrNoDatabaseName = fmt.Errorf("no database name")
 	ErrNoSchema       = fmt.Errorf("no schema")
 	ErrNoUser         = fmt.Errorf("no user")
-	ErrDatabaseDirty  = fmt.Errorf("database is dirty")
+	ErrDatabaseDirty  = database.ErrDatabaseDirty
 )
 
 type Config struct {
@@ -44,6 +43,9 @@ type Postgres struct {
 	isLocked bool
 	tx       *sql.Tx
 
+	// the dialect depends on the server version
+	dialect *database.Dialect
+
 	// Open and WithInstance need to guarantee that config is never nil
 	config *Config
 }
@@ -99,6 +101,12 @@ func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
 
 	config.User = user
 
+	query = `SELECT current_setting('server_version_num')::integer`
+	var serverVersionNum int
+	if err := instance.QueryRow(query).Scan(&serverVersionNum); err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
 	conn, err := instance.Conn(context.Background())
 
 	if err != nil {
@@ -106,9 +114,10 @@ func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
 	}
 
 	px := &Postgres{
-		conn:   conn,
-		db:     instance,
-		config: config,
+		conn:    conn,
+		db:      instance,
+		config:  config,
+		dialect: dialectFor(serverVersionNum),
 	}
 
 	return px, nil
@@ -322,85 +331,107 @@ func (p *Postgres) Query(command io.Reader, params ...interface{}) (*sql.Rows, e
 	return rows, nil
 }
 
-func (p *Postgres) ImportCSV(filePath, schemaName, tableName, delimiter string, header bool) (output string, err error) {
-	sql := fmt.Sprintf("\\COPY %s.%s FROM '%s' WITH DELIMITER '%s' CSV", schemaName, tableName, filePath, delimiter)
-	if header {
-		sql += " HEADER;"
-	} else {
-		sql += ";"
-	}
-	out, err := util.OSExec("psql", p.config.URL, "-q", "-c", sql)
+// ImportCSV streams the rows of the CSV to the table with COPY FROM STDIN on the driver's
+// connection, in the current transaction if there is one. Fields are matched to columns by position.
+// As with COPY ... CSV, unquoted empty fields are loaded as NULL and quoted empty fields as empty
+// strings.
+func (p *Postgres) ImportCSV(csvReader io.Reader, schemaName, tableName, delimiter string, header bool) (output string, err error) {
+	r, err := database.NewCSVReader(csvReader, delimiter)
 	if err != nil {
-		return out, err
+		return "", err
 	}
 
-	return out, nil
-}
+	if header {
+		if _, err = r.Read(); err == io.EOF {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+	}
 
-func (p *Postgres) Schemas() ([]string, error) {
-	rows, err := p.Query(strings.NewReader(database.SQLQuerySchemas))
+	// COPY requires a transaction
+	tx := p.tx
+	if tx == nil {
+		if tx, err = p.conn.BeginTx(context.Background(), nil); err != nil {
+			return "", &database.Error{OrigErr: err, Err: "error beginning transaction"}
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			} else if err = tx.Commit(); err != nil {
+				err = &database.Error{OrigErr: err, Err: "error committing transaction"}
+			}
+		}()
+	}
+
+	// pq sends the values of each row in COPY's text format
+	query := fmt.Sprintf("COPY %s.%s FROM STDIN", pq.QuoteIdentifier(schemaName), pq.QuoteIdentifier(tableName))
+	stmt, err := tx.PrepareContext(context.Background(), query)
 	if err != nil {
-		return nil, err
+		return "", copyError(err, query)
 	}
+	defer stmt.Close()
 
-	schemaNames := []string{}
-	for rows.Next() {
-		name := ""
-		err = rows.Scan(&name)
-		if err != nil {
-			return nil, err
+	rows := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
 		}
 
-		if name != "pg_catalog" && name != "information_schema" {
-			schemaNames = append(schemaNames, name)
+		if _, err = stmt.ExecContext(context.Background(), record...); err != nil {
+			return "", copyError(err, query)
 		}
+		rows++
 	}
 
-	return schemaNames, nil
-}
-
-func (p *Postgres) querySchemaItems(schemaName, query string) ([]*database.SchemaItemInfo, error) {
-	sql := fmt.Sprintf(query, schemaName)
-	rows, err := p.Query(strings.NewReader(sql))
-	if err != nil {
-		return nil, err
+	// executing without values ends the COPY
+	if _, err = stmt.ExecContext(context.Background()); err != nil {
+		return "", copyError(err, query)
 	}
 
-	schemaItems := []*database.SchemaItemInfo{}
-	for rows.Next() {
-		item := &database.SchemaItemInfo{}
-		err = rows.Scan(&item.ItemType, &item.SchemaName, &item.ItemName)
-		if err != nil {
-			return nil, err
+	return fmt.Sprintf("loaded %d rows into %s.%s", rows, schemaName, tableName), nil
+}
+
+func copyError(err error, query string) error {
+	if pgErr, ok := err.(*pq.Error); ok {
+		message := pgErr.Message
+		if pgErr.Where != "" {
+			message = fmt.Sprintf("%s, %s", message, pgErr.Where)
 		}
-		schemaItems = append(schemaItems, item)
+		return database.Error{OrigErr: err, Err: message, Query: []byte(query)}
 	}
+	return database.Error{OrigErr: err, Err: "copy failed", Query: []byte(query)}
+}
 
-	return schemaItems, nil
+func (p *Postgres) Schemas() ([]string, error) {
+	return database.QueryStrings(p, p.dialect.SchemasQuery)
 }
 
 func (p *Postgres) Tables(schema string) ([]*database.SchemaItemInfo, error) {
-	return p.querySchemaItems(schema, database.SQLQueryTables)
+	return database.QuerySchemaItems(p, p.dialect.TablesQuery, schema)
 }
 
 func (p *Postgres) Views(schema string) ([]*database.SchemaItemInfo, error) {
-	return p.querySchemaItems(schema, database.SQLQueryViews)
+	return database.QuerySchemaItems(p, p.dialect.ViewsQuery, schema)
 }
 
 func (p *Postgres) Functions(schema string) ([]*database.SchemaItemInfo, error) {
-	return p.querySchemaItems(schema, database.SQLQueryFunctions)
+	return database.QuerySchemaItems(p, p.dialect.FunctionsQuery, schema)
 }
 
 func (p *Postgres) Procedures(schema string) ([]*database.SchemaItemInfo, error) {
-	return p.querySchemaItems(schema, database.SQLQueryProcedures)
+	return database.QuerySchemaItems(p, p.dialect.ProceduresQuery, schema)
 }
 
 func (p *Postgres) Types(schema string) ([]*database.SchemaItemInfo, error) {
-	return p.querySchemaItems(schema, database.SQLQueryTypes)
+	return database.QuerySchemaItems(p, p.dialect.TypesQuery, schema)
 }
 
-func (p *Postgres) Extensions() ([]string, error) {
-	return nil, fmt.Errorf("not implemented")
+func (p *Postgres) Extensions() ([]*database.ExtensionInfo, error) {
+	return database.QueryExtensions(p, p.dialect.ExtensionsQuery)
 }
 
 func (p *Postgres) SchemaItems(schema string) ([]*database.SchemaItemInfo, error) {
@@ -440,27 +471,36 @@ func (p *Postgres) SchemaItems(schema string) ([]*database.SchemaItemInfo, error
 }
 
 func (p *Postgres) ForeignKeys(schema string) ([]*database.ForeignKeyInfo, error) {
-	query := fmt.Sprintf(database.SQLQueryForeignKeys, schema)
-	rows, err := p.Query(strings.NewReader(query))
-	if err != nil {
-		return nil, err
-	}
+	return database.QueryForeignKeys(p, p.dialect.ForeignKeysQuery, schema)
+}
 
-	fkInfo := []*database.ForeignKeyInfo{}
-	for rows.Next() {
-		item := &database.ForeignKeyInfo{}
-		err = rows.Scan(&item.ParentSchemaName, &item.ParentTableName, &item.ParentColumnName, &item.ChildSchemaName, &item.ChildTableName, &item.ChildColumnName)
-		if err != nil {
-			return nil, err
-		}
-		fkInfo = append(fkInfo, item)
-	}
+// Roles returns the roles except the predefined pg_ roles
+func (p *Postgres) Roles() ([]*database.RoleInfo, error) {
+	return database.QueryRoles(p, p.dialect.RolesQuery)
+}
+
+func (p *Postgres) Columns(schema, table string) ([]*database.ColumnInfo, error) {
+	return database.QueryColumns(p, p.dialect.ColumnsQuery, schema, table)
+}
+
+func (p *Postgres) Indexes(schema, table string) ([]*database.IndexInfo, error) {
+	return database.QueryIndexes(p, p.dialect.IndexesQuery, schema, table)
+}
+
+func (p *Postgres) Constraints(schema, table string) ([]*database.ConstraintInfo, error) {
+	return database.QueryConstraints(p, p.dialect.ConstraintsQuery, schema, table)
+}
+
+func (p *Postgres) Triggers(schema, table string) ([]*database.TriggerInfo, error) {
+	return database.QueryTriggers(p, p.dialect.TriggersQuery, schema, table)
+}
 
-	return fkInfo, nil
+func (p *Postgres) Definitions(definitionType, schema, name string) ([]*database.DefinitionInfo, error) {
+	return database.QueryDefinitions(p, p.dialect.DefinitionQueries[definitionType], schema, name)
 }
 
-func (p *Postgres) Roles() ([]string, error) {
-	return nil, fmt.Errorf("not implemented")
+func (p *Postgres) Dialect() *database.Dialect {
+	return p.dialect
 }
 
 func computeLineFromPos(s string, pos int) (line uint, col uint, ok bool) {
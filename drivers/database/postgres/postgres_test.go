@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"github.com/dhui/dktest"
+	"github.com/nrfta/ddsl/drivers/database"
 	dt "github.com/nrfta/ddsl/drivers/database/testing"
 )
 
@@ -29,6 +30,7 @@ var (
 		{ImageName: "postgres:9.6", Options: opts},
 		{ImageName: "postgres:10", Options: opts},
 		{ImageName: "postgres:11", Options: opts},
+		{ImageName: "postgres:12", Options: opts},
 	}
 )
 
@@ -169,6 +171,333 @@ func TestFilterCustomQuery(t *testing.T) {
 	})
 }
 
+func TestRolesAndExtensions(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		setup := "CREATE EXTENSION IF NOT EXISTS hstore; CREATE ROLE readers; CREATE ROLE reader LOGIN IN ROLE readers;"
+		if err := d.Exec(strings.NewReader(setup)); err != nil {
+			t.Fatal(err)
+		}
+
+		extensions, err := d.Extensions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, e := range extensions {
+			if e.Name == "hstore" {
+				found = true
+				if e.SchemaName != "public" || len(e.Version) == 0 {
+					t.Fatalf("unexpected hstore extension %+v", e)
+				}
+			}
+		}
+		if !found {
+			t.Fatal("expected extension hstore")
+		}
+
+		roles, err := d.Roles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := map[string]*database.RoleInfo{}
+		for _, r := range roles {
+			byName[r.Name] = r
+		}
+		if r := byName["postgres"]; r == nil || !r.Superuser || !r.Login {
+			t.Fatalf("unexpected postgres role %+v", r)
+		}
+		if r := byName["readers"]; r == nil || r.Login || len(r.MemberOf) != 0 {
+			t.Fatalf("unexpected readers role %+v", r)
+		}
+		if r := byName["reader"]; r == nil || !r.Login || r.Superuser || len(r.MemberOf) != 1 || r.MemberOf[0] != "readers" {
+			t.Fatalf("unexpected reader role %+v", r)
+		}
+	})
+}
+
+func TestTableIntrospection(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		setup := `
+			CREATE TABLE foo (id INTEGER PRIMARY KEY, code TEXT, CONSTRAINT code_check CHECK (code <> ''));
+			CREATE INDEX foo_code ON foo USING hash (code) WHERE code IS NOT NULL;
+			COMMENT ON COLUMN foo.code IS 'the code';
+			CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql;
+			CREATE TRIGGER foo_touch BEFORE INSERT OR UPDATE ON foo FOR EACH ROW EXECUTE PROCEDURE touch();`
+		if err := d.Exec(strings.NewReader(setup)); err != nil {
+			t.Fatal(err)
+		}
+
+		columns, err := d.Columns("public", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(columns) != 2 || *columns[1] != (database.ColumnInfo{
+			SchemaName: "public", TableName: "foo", ColumnName: "code", Ordinal: 2, DataType: "text",
+			Nullable: true, Comment: "the code",
+		}) || columns[0].Nullable || columns[0].DataType != "integer" {
+			t.Fatalf("unexpected columns %v", columns)
+		}
+
+		indexes, err := d.Indexes("public", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(indexes) != 2 || indexes[0].IndexName != "foo_code" || indexes[0].Method != "hash" ||
+			indexes[0].Predicate != "code IS NOT NULL" || indexes[1].IndexName != "foo_pkey" || !indexes[1].Unique ||
+			len(indexes[1].Columns) != 1 || indexes[1].Columns[0] != "id" {
+			t.Fatalf("unexpected indexes %v", indexes)
+		}
+
+		constraints, err := d.Constraints("public", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(constraints) != 2 || constraints[0].ConstraintType != "CHECK" ||
+			constraints[1].ConstraintType != "PRIMARY KEY" || constraints[1].Definition != "PRIMARY KEY (id)" {
+			t.Fatalf("unexpected constraints %v", constraints)
+		}
+
+		triggers, err := d.Triggers("public", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(triggers) != 1 || triggers[0].Timing != "BEFORE" || strings.Join(triggers[0].Events, ",") != "INSERT,UPDATE" ||
+			triggers[0].Function != "public.touch" {
+			t.Fatalf("unexpected triggers %v", triggers)
+		}
+	})
+}
+
+func TestDefinitions(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		var serverVersionNum int
+		if err := d.(*Postgres).conn.QueryRowContext(context.Background(), "SHOW server_version_num").Scan(&serverVersionNum); err != nil {
+			t.Fatal(err)
+		}
+
+		// identity columns are new in Postgres 10, and procedures and ON ROUTINE in 11
+		identity, routine := "", "FUNCTION"
+		if serverVersionNum >= 100000 {
+			identity = " GENERATED ALWAYS AS IDENTITY"
+		}
+		if serverVersionNum >= 110000 {
+			routine = "ROUTINE"
+		}
+
+		setup := `
+			CREATE TYPE mood AS ENUM ('sad', 'happy');
+			CREATE TABLE parent (id INTEGER` + identity + ` PRIMARY KEY, code TEXT NOT NULL DEFAULT 'none');
+			CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER, feeling mood, CONSTRAINT child_parent UNIQUE (parent_id));
+			ALTER TABLE child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES parent (id);
+			CREATE INDEX child_feeling ON child (feeling);
+			CREATE ROLE reader;
+			GRANT SELECT, INSERT ON parent TO reader;
+			CREATE FUNCTION add_one(i integer) RETURNS integer AS 'SELECT i + 1' LANGUAGE sql;
+			CREATE AGGREGATE add_one(bigint) (SFUNC = int8pl, STYPE = bigint);
+			REVOKE EXECUTE ON FUNCTION add_one(integer) FROM PUBLIC;
+			GRANT EXECUTE ON FUNCTION add_one(integer) TO reader;`
+		if err := d.Exec(strings.NewReader(setup)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			definitionType string
+			name           string
+			expected       []database.DefinitionInfo
+		}{
+			{database.DefinitionTypeSchema, "public", []database.DefinitionInfo{{
+				Create: "CREATE SCHEMA public;", Drop: "DROP SCHEMA public;",
+			}}},
+			{database.SchemaItemTypeTable, "parent", []database.DefinitionInfo{{
+				Create: "CREATE TABLE public.parent (\n" +
+					"    id integer" + identity + " NOT NULL,\n" +
+					"    code text DEFAULT 'none'::text NOT NULL,\n" +
+					"    CONSTRAINT parent_pkey PRIMARY KEY (id)\n);",
+				Drop: "DROP TABLE public.parent;",
+			}}},
+			{database.SchemaItemTypeType, "mood", []database.DefinitionInfo{{
+				Create: "CREATE TYPE public.mood AS ENUM ('sad', 'happy');", Drop: "DROP TYPE public.mood;",
+			}}},
+			{database.DefinitionTypeIndexes, "child", []database.DefinitionInfo{{
+				Create: "CREATE INDEX child_feeling ON public.child USING btree (feeling);",
+				Drop:   "DROP INDEX public.child_feeling;",
+			}}},
+			{database.DefinitionTypeConstraints, "child", []database.DefinitionInfo{{
+				Create: "ALTER TABLE public.child ADD CONSTRAINT child_parent UNIQUE (parent_id);",
+				Drop:   "ALTER TABLE public.child DROP CONSTRAINT child_parent;",
+			}}},
+			{database.DefinitionTypeForeignKeys, "child", []database.DefinitionInfo{{
+				Create: "ALTER TABLE public.child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES parent(id);",
+				Drop:   "ALTER TABLE public.child DROP CONSTRAINT child_parent_fk;",
+			}}},
+			{database.DefinitionTypeTablePrivileges, "parent", []database.DefinitionInfo{{
+				Create: "GRANT INSERT, SELECT ON public.parent TO reader;",
+				Drop:   "REVOKE INSERT, SELECT ON public.parent FROM reader;",
+			}}},
+			{database.DefinitionTypeRoutinePrivileges, "add_one", []database.DefinitionInfo{{
+				Create: "GRANT EXECUTE ON " + routine + " public.add_one(i integer) TO reader;",
+				Drop:   "REVOKE EXECUTE ON " + routine + " public.add_one(i integer) FROM reader;",
+			}}},
+		} {
+			definitions, err := d.Definitions(tc.definitionType, "public", tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(definitions) != len(tc.expected) {
+				t.Fatalf("%s: unexpected definitions %v", tc.definitionType, definitions)
+			}
+			for i := range definitions {
+				if *definitions[i] != tc.expected[i] {
+					t.Fatalf("%s: unexpected definition %v", tc.definitionType, definitions[i])
+				}
+			}
+		}
+
+		// the aggregate of the same name is not a function
+		functions, err := d.Definitions(database.SchemaItemTypeFunction, "public", "add_one")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(functions) != 1 || functions[0].Drop != "DROP FUNCTION public.add_one(i integer);" {
+			t.Fatalf("unexpected function definitions %v", functions)
+		}
+	})
+}
+
+func Test_definitionQueries(t *testing.T) {
+	for _, tc := range []struct {
+		serverVersionNum int
+		present, absent  []string
+	}{
+		{90400, []string{"proisagg", "ON FUNCTION"}, []string{"attidentity", "prokind", "attgenerated", "ON ROUTINE"}},
+		{100000, []string{"attidentity", "proisagg"}, []string{"prokind", "attgenerated", "ON ROUTINE"}},
+		{110000, []string{"attidentity", "prokind", "ON ROUTINE"}, []string{"proisagg", "attgenerated"}},
+		{120000, []string{"attidentity", "prokind", "attgenerated", "ON ROUTINE"}, []string{"proisagg"}},
+	} {
+		queries := strings.Join([]string{
+			definitionQueries(tc.serverVersionNum)[database.SchemaItemTypeTable],
+			definitionQueries(tc.serverVersionNum)[database.SchemaItemTypeFunction],
+			definitionQueries(tc.serverVersionNum)[database.DefinitionTypeRoutinePrivileges],
+		}, "\n")
+		for _, p := range tc.present {
+			if !strings.Contains(queries, p) {
+				t.Errorf("%d: expected %s in the definition queries", tc.serverVersionNum, p)
+			}
+		}
+		for _, a := range tc.absent {
+			if strings.Contains(queries, a) {
+				t.Errorf("%d: unexpected %s in the definition queries", tc.serverVersionNum, a)
+			}
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		if err := d.Exec(strings.NewReader(`CREATE TABLE "Foo" (id INTEGER, name TEXT)`)); err != nil {
+			t.Fatal(err)
+		}
+
+		// an unquoted empty field is NULL and a quoted one an empty string
+		output, err := d.ImportCSV(strings.NewReader("id,name\n1,\"t,wo\"\n2,\n3,\"\"\n"), "public", "Foo", ",", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != "loaded 3 rows into public.Foo" {
+			t.Fatalf("unexpected output %s", output)
+		}
+
+		// rows imported in a transaction are rolled back with it
+		if err := d.Begin(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.ImportCSV(strings.NewReader("4;four\n"), "public", "Foo", ";", false); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+
+		var count, nulls, empties int
+		var name string
+		query := `SELECT COUNT(*), COUNT(*) - COUNT(name), COUNT(*) FILTER (WHERE name = ''), MAX(name) FROM "Foo"`
+		if err := d.(*Postgres).conn.QueryRowContext(context.Background(), query).Scan(&count, &nulls, &empties, &name); err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 || nulls != 1 || empties != 1 || name != "t,wo" {
+			t.Fatalf("unexpected import: %d rows, %d nulls, %d empty names, max name %s", count, nulls, empties, name)
+		}
+	})
+}
+
 func TestWithSchema(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
@@ -0,0 +1,59 @@
+package sqlite
+
+import "github.com/nrfta/ddsl/drivers/database"
+
+// dialect has no schema item or trigger queries since the catalog of each attached database is a
+// table of that database; see querySchemaItems. The table queries use pragma functions, which take
+// the schema as an argument, and refer to the schema and table as ?1 and ?2.
+var dialect = &database.Dialect{
+	Placeholder:      database.QuestionMarkPlaceholder,
+	SchemasQuery:     "SELECT name FROM pragma_database_list WHERE name <> 'temp' ORDER BY seq",
+	TableExistsQuery: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+	// hidden is 2 for virtual and 3 for stored generated columns, whose expressions are not recorded
+	// apart from the table's SQL. Their type ends with GENERATED ALWAYS when declared with it.
+	ColumnsQuery: `
+		SELECT ?1, ?2, name, cid + 1,
+			CASE WHEN hidden IN (2, 3) AND instr(upper(type), ' GENERATED ALWAYS') > 0
+				THEN substr(type, 1, instr(upper(type), ' GENERATED ALWAYS') - 1)
+				ELSE type
+			END,
+			"notnull" = 0, COALESCE(dflt_value, ''), '',
+			CASE hidden WHEN 2 THEN 'VIRTUAL' WHEN 3 THEN 'STORED' ELSE '' END, ''
+		FROM pragma_table_xinfo(?2, ?1)
+		ORDER BY cid
+	`,
+	IndexesQuery: `
+		SELECT ?1, ?2, il.name,
+			COALESCE((
+				SELECT group_concat(name, char(31)) FROM (
+					SELECT COALESCE(ii.name, '<expression>') AS name FROM pragma_index_info(il.name, ?1) AS ii ORDER BY ii.seqno
+				)
+			), '') AS columns,
+			il."unique", '' AS method, '' AS predicate
+		FROM pragma_index_list(?2, ?1) AS il
+		ORDER BY il.name
+	`,
+	// SQLite does not record CHECK constraints apart from the table's SQL
+	ConstraintsQuery: `
+		SELECT * FROM (
+			SELECT ?1, ?2, '' AS name, 'PRIMARY KEY' AS constraint_type,
+				'PRIMARY KEY (' || group_concat(name, ', ') || ')' AS definition
+			FROM (SELECT name FROM pragma_table_info(?2, ?1) WHERE pk > 0 ORDER BY pk)
+		) WHERE definition IS NOT NULL
+		UNION ALL
+		SELECT ?1, ?2, il.name, 'UNIQUE',
+			'UNIQUE (' || (
+				SELECT group_concat(name, ', ') FROM (
+					SELECT ii.name FROM pragma_index_info(il.name, ?1) AS ii ORDER BY ii.seqno
+				)
+			) || ')'
+		FROM pragma_index_list(?2, ?1) AS il
+		WHERE il.origin = 'u'
+		UNION ALL
+		SELECT ?1, ?2, '', 'FOREIGN KEY',
+			'FOREIGN KEY (' || group_concat(fk."from", ', ') || ') REFERENCES ' || fk."table" ||
+			' (' || group_concat(fk."to", ', ') || ')'
+		FROM pragma_foreign_key_list(?2, ?1) AS fk
+		GROUP BY fk.id
+	`,
+}
@@ -0,0 +1,501 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nurl "net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/nrfta/ddsl/drivers/database"
+)
+
+func init() {
+	db := SQLite{}
+	database.Register("sqlite", &db)
+	database.Register("sqlite3", &db)
+}
+
+var (
+	ErrNilConfig      = fmt.Errorf("no config")
+	ErrNoDatabaseName = fmt.Errorf("no database name")
+	ErrDatabaseDirty  = database.ErrDatabaseDirty
+)
+
+type Config struct {
+	DatabaseName string
+	URL          string
+}
+
+// SQLite runs commands against a SQLite database file. Attached databases are its schemas,
+// main being the database file itself.
+type SQLite struct {
+	// attached databases and transactions belong to a connection, so everything uses the same one
+	conn     *sql.Conn
+	db       *sql.DB
+	isLocked bool
+	tx       *sql.Tx
+
+	// Open and WithInstance need to guarantee that config is never nil
+	config *Config
+}
+
+// compile-time interface compliance
+var _ database.Driver = (*SQLite)(nil)
+
+func Register() {
+	// do nothing, but call to force compiler to accept import without use
+}
+
+func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+
+	if len(config.DatabaseName) == 0 {
+		return nil, ErrNoDatabaseName
+	}
+
+	if err := instance.Ping(); err != nil {
+		return nil, err
+	}
+
+	conn, err := instance.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	sx := &SQLite{
+		conn:   conn,
+		db:     instance,
+		config: config,
+	}
+
+	return sx, nil
+}
+
+func (s *SQLite) Open(url string) (database.Driver, error) {
+	surl, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// concat host and path to restore full path
+	// host might be `.`
+	p := surl.Opaque
+	if len(p) == 0 {
+		p = surl.Host + surl.Path
+	}
+	if len(p) == 0 {
+		return nil, ErrNoDatabaseName
+	}
+
+	dsn := "file:" + p
+	if len(surl.RawQuery) > 0 {
+		dsn += "?" + surl.RawQuery
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(p)
+	sx, err := WithInstance(db, &Config{
+		DatabaseName: strings.TrimSuffix(name, filepath.Ext(name)),
+		URL:          url,
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return sx, nil
+}
+
+func (s *SQLite) Close() error {
+	connErr := s.conn.Close()
+	dbErr := s.db.Close()
+	if connErr != nil || dbErr != nil {
+		return fmt.Errorf("conn: %v, db: %v", connErr, dbErr)
+	}
+	return nil
+}
+
+// Lock only guards against concurrent use of the driver. SQLite locks the database file itself
+// while writing.
+func (s *SQLite) Lock() error {
+	if s.isLocked {
+		return database.ErrLocked
+	}
+	s.isLocked = true
+	return nil
+}
+
+func (s *SQLite) Unlock() error {
+	s.isLocked = false
+	return nil
+}
+
+// User returns an empty string since SQLite has no users
+func (s *SQLite) User() string {
+	return ""
+}
+
+func (s *SQLite) DatabaseName() string {
+	return s.config.DatabaseName
+}
+
+func (s *SQLite) Begin() error {
+	if s.tx != nil {
+		return &database.Error{Err: "connection is already in transaction"}
+	}
+
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "error beginning transaction"}
+	}
+
+	s.tx = tx
+
+	return nil
+}
+
+func (s *SQLite) Rollback() error {
+	if s.tx == nil {
+		return &database.Error{Err: "connection is not in transaction"}
+	}
+
+	err := s.tx.Rollback()
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "error rolling back transaction"}
+	}
+
+	s.tx = nil
+
+	return nil
+}
+
+func (s *SQLite) Commit() error {
+	if s.tx == nil {
+		return &database.Error{Err: "connection is not in transaction"}
+	}
+
+	err := s.tx.Commit()
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "error committing transaction"}
+	}
+
+	s.tx = nil
+
+	return nil
+}
+
+func (s *SQLite) Exec(command io.Reader, params ...interface{}) error {
+	cmdBytes, err := ioutil.ReadAll(command)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.conn.ExecContext(context.Background(), string(cmdBytes), params...); err != nil {
+		return commandError(err, cmdBytes)
+	}
+
+	return nil
+}
+
+func (s *SQLite) Query(command io.Reader, params ...interface{}) (*sql.Rows, error) {
+	cmdBytes, err := ioutil.ReadAll(command)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn.QueryContext(context.Background(), string(cmdBytes), params...)
+	if err != nil {
+		return nil, commandError(err, cmdBytes)
+	}
+
+	return rows, nil
+}
+
+func commandError(err error, cmdBytes []byte) error {
+	if sqliteErr, ok := err.(sqlite3.Error); ok {
+		return database.Error{OrigErr: err, Err: sqliteErr.Error(), Query: cmdBytes}
+	}
+	return database.Error{OrigErr: err, Err: "command failed", Query: cmdBytes}
+}
+
+// ImportCSV inserts the rows of the CSV into the table, matching fields to columns by position.
+// The header, if any, is skipped. As with COPY ... CSV in Postgres, unquoted empty fields are
+// inserted as NULL and quoted empty fields as empty strings.
+func (s *SQLite) ImportCSV(csvReader io.Reader, schemaName, tableName, delimiter string, header bool) (output string, err error) {
+	r, err := database.NewCSVReader(csvReader, delimiter)
+	if err != nil {
+		return "", err
+	}
+
+	if header {
+		if _, err = r.Read(); err == io.EOF {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	var stmt *sql.Stmt
+	var query string
+	rows := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		// the first record gives the number of values
+		if stmt == nil {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(record)), ", ")
+			query = fmt.Sprintf("INSERT INTO %s.%s VALUES (%s)", quoteIdentifier(schemaName), quoteIdentifier(tableName), placeholders)
+			if stmt, err = s.conn.PrepareContext(context.Background(), query); err != nil {
+				return "", commandError(err, []byte(query))
+			}
+			defer stmt.Close()
+		}
+
+		if _, err = stmt.ExecContext(context.Background(), record...); err != nil {
+			return "", commandError(err, []byte(query))
+		}
+		rows++
+	}
+
+	return fmt.Sprintf("loaded %d rows into %s.%s", rows, schemaName, tableName), nil
+}
+
+// Schemas returns the names of the attached databases, except temp
+func (s *SQLite) Schemas() ([]string, error) {
+	return database.QueryStrings(s, dialect.SchemasQuery)
+}
+
+func (s *SQLite) querySchemaItems(schemaName, itemType, sqliteType string) ([]*database.SchemaItemInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT name FROM %s.sqlite_master
+		WHERE type = ? AND name NOT LIKE 'sqlite_%%'
+		ORDER BY name`, quoteIdentifier(schemaName))
+	rows, err := s.Query(strings.NewReader(query), sqliteType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemaItems := []*database.SchemaItemInfo{}
+	for rows.Next() {
+		item := &database.SchemaItemInfo{ItemType: itemType, SchemaName: schemaName}
+		if err = rows.Scan(&item.ItemName); err != nil {
+			return nil, err
+		}
+		schemaItems = append(schemaItems, item)
+	}
+
+	return schemaItems, rows.Err()
+}
+
+func (s *SQLite) Tables(schema string) ([]*database.SchemaItemInfo, error) {
+	return s.querySchemaItems(schema, database.SchemaItemTypeTable, "table")
+}
+
+func (s *SQLite) Views(schema string) ([]*database.SchemaItemInfo, error) {
+	return s.querySchemaItems(schema, database.SchemaItemTypeView, "view")
+}
+
+// Functions returns no functions since SQLite functions are defined by the application
+func (s *SQLite) Functions(schema string) ([]*database.SchemaItemInfo, error) {
+	return []*database.SchemaItemInfo{}, nil
+}
+
+// Procedures returns no procedures since SQLite has none
+func (s *SQLite) Procedures(schema string) ([]*database.SchemaItemInfo, error) {
+	return []*database.SchemaItemInfo{}, nil
+}
+
+// Types returns no types since SQLite has no user defined types
+func (s *SQLite) Types(schema string) ([]*database.SchemaItemInfo, error) {
+	return []*database.SchemaItemInfo{}, nil
+}
+
+// Extensions returns no extensions since SQLite does not list loaded extensions
+func (s *SQLite) Extensions() ([]*database.ExtensionInfo, error) {
+	return []*database.ExtensionInfo{}, nil
+}
+
+func (s *SQLite) SchemaItems(schema string) ([]*database.SchemaItemInfo, error) {
+	items := []*database.SchemaItemInfo{}
+
+	tables, err := s.Tables(schema)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, tables...)
+
+	views, err := s.Views(schema)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, views...)
+
+	return items, nil
+}
+
+// ForeignKeys returns the foreign keys of the tables in the schema. SQLite foreign keys cannot
+// reference tables in other schemas.
+func (s *SQLite) ForeignKeys(schema string) ([]*database.ForeignKeyInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT m.name, fk."from", fk."table", fk."to"
+		FROM %s.sqlite_master AS m
+		JOIN pragma_foreign_key_list(m.name, ?) AS fk
+		WHERE m.type = 'table'
+		ORDER BY m.name, fk.id, fk.seq`, quoteIdentifier(schema))
+	rows, err := s.Query(strings.NewReader(query), schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fkInfo := []*database.ForeignKeyInfo{}
+	for rows.Next() {
+		item := &database.ForeignKeyInfo{ParentSchemaName: schema, ChildSchemaName: schema}
+		err = rows.Scan(&item.ParentTableName, &item.ParentColumnName, &item.ChildTableName, &item.ChildColumnName)
+		if err != nil {
+			return nil, err
+		}
+		fkInfo = append(fkInfo, item)
+	}
+
+	return fkInfo, rows.Err()
+}
+
+// Roles returns no roles since SQLite has no users
+func (s *SQLite) Roles() ([]*database.RoleInfo, error) {
+	return []*database.RoleInfo{}, nil
+}
+
+func (s *SQLite) Columns(schema, table string) ([]*database.ColumnInfo, error) {
+	return database.QueryColumns(s, dialect.ColumnsQuery, schema, table)
+}
+
+func (s *SQLite) Indexes(schema, table string) ([]*database.IndexInfo, error) {
+	return database.QueryIndexes(s, dialect.IndexesQuery, schema, table)
+}
+
+func (s *SQLite) Constraints(schema, table string) ([]*database.ConstraintInfo, error) {
+	return database.QueryConstraints(s, dialect.ConstraintsQuery, schema, table)
+}
+
+// Triggers returns the triggers on the table. Their timing and event are read from their SQL and
+// Function is their statements since SQLite triggers have no functions.
+func (s *SQLite) Triggers(schema, table string) ([]*database.TriggerInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT name, sql FROM %s.sqlite_master
+		WHERE type = 'trigger' AND tbl_name = ?
+		ORDER BY name`, quoteIdentifier(schema))
+	rows, err := s.Query(strings.NewReader(query), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggers := []*database.TriggerInfo{}
+	for rows.Next() {
+		item := &database.TriggerInfo{SchemaName: schema, TableName: table, Events: []string{}}
+		sql := ""
+		if err = rows.Scan(&item.TriggerName, &sql); err != nil {
+			return nil, err
+		}
+		if m := triggerPattern.FindStringSubmatch(sql); m != nil {
+			item.Timing = "BEFORE"
+			if timing := strings.Fields(strings.ToUpper(m[1])); len(timing) > 0 {
+				item.Timing = strings.Join(timing, " ")
+			}
+			item.Events = []string{strings.ToUpper(m[2])}
+			item.Function = strings.TrimSpace(m[3])
+		}
+		triggers = append(triggers, item)
+	}
+
+	return triggers, rows.Err()
+}
+
+func (s *SQLite) Dialect() *database.Dialect {
+	return dialect
+}
+
+// definitionTypes are the sqlite_master types of the objects with definitions
+var definitionTypes = map[string]string{
+	database.SchemaItemTypeTable:    "table",
+	database.SchemaItemTypeView:     "view",
+	database.DefinitionTypeIndexes:  "index",
+	database.DefinitionTypeTriggers: "trigger",
+}
+
+// Definitions returns the SQL of the object recorded in sqlite_master, qualified by the schema.
+// Tables are created with their constraints and foreign keys since SQLite cannot add them to a
+// table. SQLite has no schema statements, functions, procedures, types or privileges.
+func (s *SQLite) Definitions(definitionType, schema, name string) ([]*database.DefinitionInfo, error) {
+	sqliteType, ok := definitionTypes[definitionType]
+	if !ok {
+		return []*database.DefinitionInfo{}, nil
+	}
+
+	// indexes and triggers are looked up by their table. Indexes of constraints have no SQL.
+	column := "name"
+	if definitionType == database.DefinitionTypeIndexes || definitionType == database.DefinitionTypeTriggers {
+		column = "tbl_name"
+	}
+	query := fmt.Sprintf(`
+		SELECT name, sql FROM %s.sqlite_master
+		WHERE type = ? AND %s = ? AND sql IS NOT NULL
+		ORDER BY name`, quoteIdentifier(schema), column)
+	rows, err := s.Query(strings.NewReader(query), sqliteType, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := []*database.DefinitionInfo{}
+	for rows.Next() {
+		objectName, sql := "", ""
+		if err = rows.Scan(&objectName, &sql); err != nil {
+			return nil, err
+		}
+
+		// sqlite_master has the statements without their schema
+		qualifiedName := quoteIdentifier(schema) + "." + quoteIdentifier(objectName)
+		if loc := createPattern.FindStringIndex(sql); loc != nil {
+			sql = sql[:loc[1]] + quoteIdentifier(schema) + "." + sql[loc[1]:]
+		}
+
+		definitions = append(definitions, &database.DefinitionInfo{
+			Create: sql + ";",
+			Drop:   fmt.Sprintf("DROP %s %s;", strings.ToUpper(sqliteType), qualifiedName),
+		})
+	}
+
+	return definitions, rows.Err()
+}
+
+// createPattern matches the start of a statement in sqlite_master up to the name of the object
+var createPattern = regexp.MustCompile(`^CREATE (UNIQUE |VIRTUAL )?(TABLE|INDEX|VIEW|TRIGGER) `)
+
+// triggerPattern matches the timing, event and statements of a CREATE TRIGGER statement
+var triggerPattern = regexp.MustCompile(
+	`(?is)^\s*CREATE\s+.*?TRIGGER\s+.*?\s(BEFORE\s+|AFTER\s+|INSTEAD\s+OF\s+)?(DELETE|INSERT|UPDATE)\b.*?\sON\s.*?\bBEGIN\b(.*)\bEND\s*;?\s*$`)
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nrfta/ddsl/drivers/database"
+	dt "github.com/nrfta/ddsl/drivers/database/testing"
+)
+
+func openTemp(t *testing.T) (database.Driver, func()) {
+	dir, err := ioutil.TempDir("", "ddsl-sqlite-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := (&SQLite{}).Open("sqlite://" + path.Join(dir, "foo_database.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return d, func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func exec(t *testing.T, d database.Driver, sql string) {
+	if err := d.Exec(strings.NewReader(sql)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	if d.DatabaseName() != "foo_database" {
+		t.Fatalf("unexpected database name %s", d.DatabaseName())
+	}
+
+	dt.Test(t, d, []byte("SELECT ?"), 1)
+}
+
+func TestMultiStatement(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	exec(t, d, "CREATE TABLE foo (foo text); CREATE TABLE bar (bar text);")
+
+	// make sure second table exists
+	var exists bool
+	if err := d.(*SQLite).conn.QueryRowContext(context.Background(), "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'bar')").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatalf("expected table bar to exist")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	if err := d.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, d, "CREATE TABLE foo (foo text)")
+	if err := d.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := d.Tables("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Fatalf("expected no tables after rollback, got %v", tables)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	exec(t, d, `
+		ATTACH DATABASE ':memory:' AS audit;
+		CREATE TABLE parent (id INTEGER PRIMARY KEY);
+		CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id));
+		CREATE VIEW children AS SELECT * FROM child;
+		CREATE TABLE audit.log (entry TEXT);`)
+
+	schemas, err := d.Schemas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != 2 || schemas[0] != "main" || schemas[1] != "audit" {
+		t.Fatalf("unexpected schemas %v", schemas)
+	}
+
+	items, err := d.SchemaItems("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"TABLE main.child", "TABLE main.parent", "VIEW main.children"}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %v, got %d items", expected, len(items))
+	}
+	for i, item := range items {
+		if s := item.ItemType + " " + item.SchemaName + "." + item.ItemName; s != expected[i] {
+			t.Fatalf("expected %s, got %s", expected[i], s)
+		}
+	}
+
+	tables, err := d.Tables("audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].ItemName != "log" || tables[0].SchemaName != "audit" {
+		t.Fatalf("unexpected tables in audit %v", tables)
+	}
+
+	fks, err := d.ForeignKeys("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fks) != 1 || *fks[0] != (database.ForeignKeyInfo{
+		ParentSchemaName: "main", ParentTableName: "child", ParentColumnName: "parent_id",
+		ChildSchemaName: "main", ChildTableName: "parent", ChildColumnName: "id",
+	}) {
+		t.Fatalf("unexpected foreign keys %v", fks)
+	}
+}
+
+func TestTableIntrospection(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	exec(t, d, `
+		ATTACH DATABASE ':memory:' AS audit;
+		CREATE TABLE audit.parent (id INTEGER PRIMARY KEY, code TEXT NOT NULL DEFAULT 'none' UNIQUE);
+		CREATE TABLE audit.child (
+			a INTEGER, b INTEGER, parent_id INTEGER REFERENCES parent (id),
+			total INTEGER GENERATED ALWAYS AS (a + b) STORED,
+			PRIMARY KEY (a, b)
+		);
+		CREATE INDEX audit.child_parent ON child (parent_id, lower(b));
+		CREATE TRIGGER audit.child_insert AFTER INSERT ON child BEGIN SELECT 1; END;`)
+
+	columns, err := d.Columns("audit", "parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 2 || *columns[1] != (database.ColumnInfo{
+		SchemaName: "audit", TableName: "parent", ColumnName: "code", Ordinal: 2, DataType: "TEXT",
+		Nullable: false, Default: "'none'", Generated: "",
+	}) {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+
+	columns, err = d.Columns("audit", "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 4 || columns[3].ColumnName != "total" || columns[3].DataType != "INTEGER" ||
+		columns[3].Generated != "STORED" {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+
+	indexes, err := d.Indexes("audit", "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 2 || indexes[0].IndexName != "child_parent" || indexes[0].Unique ||
+		strings.Join(indexes[0].Columns, ",") != "parent_id,<expression>" ||
+		!indexes[1].Unique || strings.Join(indexes[1].Columns, ",") != "a,b" {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+
+	constraints, err := d.Constraints("audit", "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	definitions := []string{}
+	for _, c := range constraints {
+		if c.SchemaName != "audit" || c.TableName != "child" {
+			t.Fatalf("unexpected constraint %v", c)
+		}
+		definitions = append(definitions, c.Definition)
+	}
+	if strings.Join(definitions, "; ") != "PRIMARY KEY (a, b); FOREIGN KEY (parent_id) REFERENCES parent (id)" {
+		t.Fatalf("unexpected constraints %v", definitions)
+	}
+
+	triggers, err := d.Triggers("audit", "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers) != 1 || triggers[0].TriggerName != "child_insert" || triggers[0].Timing != "AFTER" ||
+		len(triggers[0].Events) != 1 || triggers[0].Events[0] != "INSERT" || triggers[0].Function != "SELECT 1;" {
+		t.Fatalf("unexpected triggers %v", triggers)
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	exec(t, d, `
+		ATTACH DATABASE ':memory:' AS audit;
+		CREATE TABLE audit.parent (id INTEGER PRIMARY KEY, code TEXT UNIQUE);
+		CREATE INDEX audit.parent_code ON parent (lower(code));
+		CREATE TRIGGER audit.parent_insert AFTER INSERT ON parent BEGIN SELECT 1; END;
+		CREATE VIEW audit.codes AS SELECT code FROM parent;`)
+
+	for _, tc := range []struct {
+		definitionType string
+		name           string
+		expected       []database.DefinitionInfo
+	}{
+		{database.SchemaItemTypeTable, "parent", []database.DefinitionInfo{{
+			Create: `CREATE TABLE "audit".parent (id INTEGER PRIMARY KEY, code TEXT UNIQUE);`,
+			Drop:   `DROP TABLE "audit"."parent";`,
+		}}},
+		{database.SchemaItemTypeView, "codes", []database.DefinitionInfo{{
+			Create: `CREATE VIEW "audit".codes AS SELECT code FROM parent;`,
+			Drop:   `DROP VIEW "audit"."codes";`,
+		}}},
+		{database.DefinitionTypeIndexes, "parent", []database.DefinitionInfo{{
+			Create: `CREATE INDEX "audit".parent_code ON parent (lower(code));`,
+			Drop:   `DROP INDEX "audit"."parent_code";`,
+		}}},
+		{database.DefinitionTypeTriggers, "parent", []database.DefinitionInfo{{
+			Create: `CREATE TRIGGER "audit".parent_insert AFTER INSERT ON parent BEGIN SELECT 1; END;`,
+			Drop:   `DROP TRIGGER "audit"."parent_insert";`,
+		}}},
+		{database.DefinitionTypeConstraints, "parent", []database.DefinitionInfo{}},
+		{database.DefinitionTypeSchema, "audit", []database.DefinitionInfo{}},
+	} {
+		definitions, err := d.Definitions(tc.definitionType, "audit", tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(definitions) != len(tc.expected) {
+			t.Fatalf("%s: unexpected definitions %v", tc.definitionType, definitions)
+		}
+		for i := range definitions {
+			if *definitions[i] != tc.expected[i] {
+				t.Fatalf("%s: unexpected definition %v", tc.definitionType, definitions[i])
+			}
+		}
+	}
+
+	// the definitions recreate the objects in their schema
+	exec(t, d, `
+		DROP VIEW "audit"."codes";
+		DROP TABLE "audit"."parent";
+		CREATE TABLE "audit".parent (id INTEGER PRIMARY KEY, code TEXT UNIQUE);
+		CREATE INDEX "audit".parent_code ON parent (lower(code));
+		CREATE VIEW "audit".codes AS SELECT code FROM parent;`)
+}
+
+func TestImportCSV(t *testing.T) {
+	d, done := openTemp(t)
+	defer done()
+
+	exec(t, d, "CREATE TABLE foo (id INTEGER, name TEXT)")
+
+	// the header is skipped and fields are matched to columns by position
+	if _, err := d.ImportCSV(strings.NewReader("key,label\n1,bar\n2,\n4,\"\"\n"), "main", "foo", ",", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ImportCSV(strings.NewReader("3;baz\n"), "main", "foo", ";", false); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := d.Query(strings.NewReader("SELECT id, COALESCE(name, 'NULL') FROM foo ORDER BY id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, id+":"+name)
+	}
+	if strings.Join(got, " ") != "1:bar 2:NULL 3:baz 4:" {
+		t.Fatalf("unexpected rows %v", got)
+	}
+}
+
+func TestOpenWithoutPath(t *testing.T) {
+	if _, err := (&SQLite{}).Open("sqlite://"); err != ErrNoDatabaseName {
+		t.Fatalf("expected %v, got %v", ErrNoDatabaseName, err)
+	}
+}
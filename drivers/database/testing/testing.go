@@ -91,7 +91,7 @@ func TestExec(t *testing.T, d database.Driver, command io.Reader, params []inter
 		t.Fatal("command can't be nil")
 	}
 
-	if err := d.Exec(command, params); err != nil {
+	if err := d.Exec(command, params...); err != nil {
 		t.Fatal(err)
 	}
 }
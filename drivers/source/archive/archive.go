@@ -0,0 +1,252 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nrfta/ddsl/drivers/source"
+)
+
+func init() {
+	source.Register("tar", &Archive{})
+	source.Register("zip", &Archive{})
+}
+
+// Archive reads files straight out of a tar (optionally gzip compressed) or zip archive.
+type Archive struct {
+	url         string
+	format      string
+	archivePath string
+	prefix      string
+	files       []string
+	contents    map[string]func() (io.ReadCloser, error)
+	zipReader   *zip.ReadCloser
+}
+
+func Register() {
+	// do nothing, but call to force compiler to accept import without use
+}
+
+func (a *Archive) Open(url string) (source.Driver, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(u.Fragment) > 0 {
+		return nil, fmt.Errorf("archive source driver: refs are not supported")
+	}
+
+	// concat host and path to restore full path
+	// host might be `.`
+	p := u.Opaque
+	if len(p) == 0 {
+		p = u.Host + u.Path
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("archive source driver: no archive path")
+	}
+	if p, err = filepath.Abs(p); err != nil {
+		return nil, err
+	}
+
+	archivePath, prefix, err := splitArchivePath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	na := &Archive{
+		url:         url,
+		format:      u.Scheme,
+		archivePath: archivePath,
+		prefix:      prefix,
+	}
+
+	return na, nil
+}
+
+func (a *Archive) Close() error {
+	if a.zipReader != nil {
+		return a.zipReader.Close()
+	}
+	return nil
+}
+
+func (a *Archive) ReadFiles(relativeDir string, fileNamePattern string) (files []*source.FileReader, err error) {
+	dr, err := a.readDirectory(relativeDir, fileNamePattern, false)
+	if err != nil {
+		return nil, err
+	}
+	return dr.FileReaders, nil
+}
+
+func (a *Archive) ReadDirectories(relativeDir string, dirNamePattern string) (files []*source.DirectoryReader, err error) {
+	dr, err := a.readDirectory(relativeDir, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return source.MatchDirectories(dr.SubDirectories, dirNamePattern), nil
+}
+
+func (a *Archive) ReadTree(relativeDir string, fileNamePattern string) (tree *source.DirectoryReader, err error) {
+	return a.readDirectory(relativeDir, fileNamePattern, true)
+}
+
+func (a *Archive) readDirectory(relativeDir string, fileNamePattern string, recursive bool) (*source.DirectoryReader, error) {
+	var re *regexp.Regexp
+	if len(fileNamePattern) > 0 {
+		re = regexp.MustCompile(fileNamePattern)
+	}
+
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+
+	return source.MakeDirectoryReader(a.files, a.root(), relativeDir, re, recursive, a.openFile), nil
+}
+
+func (a *Archive) openFile(relativePath string) (io.ReadCloser, error) {
+	open, ok := a.contents[relativePath]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path.Join(a.root(), relativePath), Err: os.ErrNotExist}
+	}
+	return open()
+}
+
+// root is the path files are named relative to: the archive path joined with the directory
+// within the archive
+func (a *Archive) root() string {
+	return path.Join(a.archivePath, a.prefix)
+}
+
+// load indexes the files of the archive below the prefix. Tar archives cannot be read at random,
+// so their contents are kept in memory.
+func (a *Archive) load() error {
+	if a.contents != nil {
+		return nil
+	}
+
+	contents := map[string]func() (io.ReadCloser, error){}
+	add := func(name string, open func() (io.ReadCloser, error)) {
+		name = path.Clean(strings.TrimPrefix(name, "/"))
+		if a.prefix != "." {
+			if !strings.HasPrefix(name, a.prefix+"/") {
+				return
+			}
+			name = name[len(a.prefix)+1:]
+		}
+		contents[name] = open
+	}
+
+	switch a.format {
+	case "zip":
+		zr, err := zip.OpenReader(a.archivePath)
+		if err != nil {
+			return err
+		}
+		a.zipReader = zr
+
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			add(f.Name, f.Open)
+		}
+
+	case "tar":
+		f, err := os.Open(a.archivePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		tr, err := newTarReader(f)
+		if err != nil {
+			return err
+		}
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+				continue
+			}
+
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			add(hdr.Name, func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(content)), nil
+			})
+		}
+
+	default:
+		return fmt.Errorf("archive source driver: unknown archive format %s", a.format)
+	}
+
+	files := []string{}
+	for name := range contents {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	a.files = files
+	a.contents = contents
+	return nil
+}
+
+// newTarReader returns a tar reader, decompressing the archive if it is gzip compressed
+func newTarReader(r io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(gr), nil
+	}
+
+	return tar.NewReader(br), nil
+}
+
+// splitArchivePath splits a path into the archive file and the directory within the archive
+func splitArchivePath(p string) (archivePath string, prefix string, err error) {
+	tail := "."
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			if info.IsDir() {
+				break
+			}
+			return dir, tail, nil
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+		tail = path.Join(filepath.Base(dir), tail)
+	}
+	return "", "", fmt.Errorf("archive source driver: no archive found in %s", p)
+}
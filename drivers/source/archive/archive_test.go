@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	st "github.com/nrfta/ddsl/drivers/source/testing"
+)
+
+var testFiles = map[string]string{
+	"release/foo_database/schemas/foo/schema.create.sql": "CREATE SCHEMA foo;",
+	"release/foo_database/schemas/foo/schema.drop.sql":   "DROP SCHEMA foo;",
+	"release/foo_database/schemas/bar/schema.create.sql": "CREATE SCHEMA bar;",
+	"release/README.md": "release notes",
+}
+
+func sortedNames(files map[string]string) []string {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeTarGz(t *testing.T, archivePath string, files map[string]string) {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, name := range sortedNames(files) {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, archivePath string, files map[string]string) {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range sortedNames(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddsl-archive-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tarPath := path.Join(dir, "db-1.4.tgz")
+	zipPath := path.Join(dir, "db-1.4.zip")
+	writeTarGz(t, tarPath, testFiles)
+	writeZip(t, zipPath, testFiles)
+
+	for _, url := range []string{
+		"tar://" + path.Join(tarPath, "release/foo_database"),
+		"zip://" + path.Join(zipPath, "release/foo_database"),
+	} {
+		d, err := (&Archive{}).Open(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := d.ReadFiles("schemas/foo", `schema\.create\.sql`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("%s: expected 1 file, got %v", url, files)
+		}
+
+		if files[0].Name != path.Join(url[len("tar://"):], "schemas/foo/schema.create.sql") {
+			t.Fatalf("%s: unexpected name %s", url, files[0].Name)
+		}
+
+		r, err := files[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "CREATE SCHEMA foo;" {
+			t.Fatalf("%s: unexpected content %q", url, content)
+		}
+
+		dirs, err := d.ReadDirectories("schemas", ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirs) != 2 || path.Base(dirs[0].DirectoryPath) != "bar" || path.Base(dirs[1].DirectoryPath) != "foo" {
+			t.Fatalf("%s: unexpected directories %v", url, dirs)
+		}
+
+		tree, err := d.ReadTree(".", `.*\.sql`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tree.SubDirectories) != 1 || len(tree.SubDirectories[0].SubDirectories) != 2 {
+			t.Fatalf("%s: unexpected tree %+v", url, tree)
+		}
+
+		files, err = d.ReadFiles("nonexistent", ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 0 {
+			t.Fatalf("%s: expected no files, got %v", url, files)
+		}
+
+		if err = d.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddsl-archive-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// archive the fixture under a release directory
+	files := map[string]string{}
+	for _, relativePath := range st.Fixture {
+		files[path.Join("release", st.DatabaseName, relativePath)] = st.Content(relativePath)
+	}
+
+	tarPath := path.Join(dir, "db.tgz")
+	zipPath := path.Join(dir, "db.zip")
+	writeTarGz(t, tarPath, files)
+	writeZip(t, zipPath, files)
+
+	st.Test(t, "tar://"+path.Join(tarPath, "release", st.DatabaseName))
+	st.Test(t, "zip://"+path.Join(zipPath, "release", st.DatabaseName))
+}
@@ -6,6 +6,7 @@ package source
 
 import (
 	"fmt"
+	"io"
 	nurl "net/url"
 	"sync"
 )
@@ -20,7 +21,8 @@ var drivers = make(map[string]Driver)
 //   2. Optionally, add a function named `WithInstance`.
 //      This function should accept an existing source instance and a Config{} struct
 //      and return a driver instance.
-//   3. Add a test that calls source/testing.go:Test()
+//   3. Add a test that writes the fixture repository of source/testing and calls
+//      source/testing.go:Test() with its URL; drivers supporting refs also call TestRef().
 //   4. Add own tests for Open(), WithInstance() (when provided) and Close().
 //      All other functions are tested by tests in source/testing.
 //      Saves you some time and makes sure all source drivers behave the same way.
@@ -40,25 +42,40 @@ type Driver interface {
 	Close() error
 
 	// ReadFiles returns `FileReader` slice for the file at the given relative directory
-	// that match the given pattern. Returns `nil` if relative path does not exist.
-	ReadFiles(relativeDir string, fileNamePattern string) (files []string, err error)
+	// that match the given pattern. Returns an empty slice if relative path does not exist.
+	ReadFiles(relativeDir string, fileNamePattern string) (files []*FileReader, err error)
 
 	// ReadDirectories returns `DirectoryReader` slice for the directories at the given relative directory
-	// that match the given pattern. Returns `nil` if relative path does not exist.
+	// that match the given pattern. Returns an empty slice if relative path does not exist.
 	ReadDirectories(relativeDir string, dirNamePattern string) (files []*DirectoryReader, err error)
 
 	// ReadTree returns a `DirectoryReader` for directory at the given relative path
-	// with the `SubDirectories` member recursively populated. Returns `nil` if the path
-	// does not exist.
+	// with the `SubDirectories` member recursively populated. Returns an empty `DirectoryReader`
+	// if the path does not exist.
 	ReadTree(relativeDir string, fileNamePattern string) (tree *DirectoryReader, err error)
 }
 
 type DirectoryReader struct {
 	DirectoryPath string
-	FileReaders []string
+	FileReaders []*FileReader
 	SubDirectories []*DirectoryReader
 }
 
+// FileReader is a file returned by a driver. Files need not exist on the host filesystem,
+// so their content must be read with `Open`.
+type FileReader struct {
+	// Name identifies the file in logs and output. It is a path ending with the
+	// file's relative path, and is unique among files returned by the same driver.
+	Name string
+
+	// Open returns the content of the file. The caller must close it.
+	Open func() (io.ReadCloser, error)
+
+	// Origin names the source the file was read from when a driver combines several
+	// sources, such as the layer URL of the overlay driver. It is empty otherwise.
+	Origin string
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	u, err := nurl.Parse(url)
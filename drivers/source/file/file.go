@@ -1,6 +1,8 @@
 package file
 
 import (
+	"fmt"
+	"io"
 	"io/ioutil"
 	nurl "net/url"
 	"os"
@@ -37,6 +39,7 @@ func (f *File) Open(url string) (source.Driver, error) {
 		p = u.Host + u.Path
 	}
 
+	// a ref names a sibling directory, e.g. foo_database#v1
 	if len(u.Fragment) > 0 {
 		p += "#" + u.Fragment
 	}
@@ -58,6 +61,12 @@ func (f *File) Open(url string) (source.Driver, error) {
 		p = abs
 	}
 
+	if len(u.Fragment) > 0 {
+		if _, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("file source driver: unknown ref '%s'", u.Fragment)
+		}
+	}
+
 	nf := &File{
 		url:        url,
 		path:       p,
@@ -80,7 +89,7 @@ func (f *File) readDirectory(relativeDir string, fileNamePattern string, recursi
 
 	dr := &source.DirectoryReader{
 		DirectoryPath: dirPath,
-		FileReaders: []string{},
+		FileReaders: []*source.FileReader{},
 		SubDirectories: []*source.DirectoryReader{},
 	}
 
@@ -98,7 +107,7 @@ func (f *File) readDirectory(relativeDir string, fileNamePattern string, recursi
 		if item.IsDir() {
 			var subdr *source.DirectoryReader
 			if recursive {
-				if subdr, err = f.readDirectory(itemPath, fileNamePattern, recursive); err != nil {
+				if subdr, err = f.readDirectory(path.Join(relativeDir, item.Name()), fileNamePattern, recursive); err != nil {
 					return nil, err
 				}
 			} else {
@@ -113,14 +122,14 @@ func (f *File) readDirectory(relativeDir string, fileNamePattern string, recursi
 				continue
 			}
 
-			dr.FileReaders = append(dr.FileReaders, itemPath)
+			dr.FileReaders = append(dr.FileReaders, newFileReader(itemPath))
 		}
 	}
 
 	return dr, nil
 }
 
-func (f *File) ReadFiles(relativeDir string, fileNamePattern string) (files []string, err error) {
+func (f *File) ReadFiles(relativeDir string, fileNamePattern string) (files []*source.FileReader, err error) {
 	dr, err := f.readDirectory(relativeDir, fileNamePattern, false)
 	if err != nil {
 		return nil, err
@@ -129,14 +138,21 @@ func (f *File) ReadFiles(relativeDir string, fileNamePattern string) (files []st
 }
 
 func (f *File) ReadDirectories(relativeDir string, dirNamePattern string) (files []*source.DirectoryReader, err error) {
-	dr, err := f.readDirectory(relativeDir, dirNamePattern, false)
+	dr, err := f.readDirectory(relativeDir, "", false)
 	if err != nil {
 		return nil, err
 	}
-	return dr.SubDirectories, nil
+	return source.MatchDirectories(dr.SubDirectories, dirNamePattern), nil
 }
 
 func (f *File) ReadTree(relativeDir string, fileNamePattern string) (tree *source.DirectoryReader, err error) {
 	return f.readDirectory(relativeDir, fileNamePattern, true)
 }
 
+func newFileReader(filePath string) *source.FileReader {
+	return &source.FileReader{
+		Name: filePath,
+		Open: func() (io.ReadCloser, error) { return os.Open(filePath) },
+	}
+}
+
@@ -0,0 +1,50 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	st "github.com/nrfta/ddsl/drivers/source/testing"
+)
+
+func Test(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddsl-file-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	st.Test(t, "file://"+st.WriteFixture(t, dir))
+}
+
+func TestRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddsl-file-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fixture is written to foo_database, which is renamed to the sibling directory of ref v1
+	dbPath := st.WriteFixture(t, dir)
+	if err = os.Rename(dbPath, dbPath+"#v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	st.TestRef(t, "file://"+dbPath, "v1")
+}
+
+func TestOpenRelative(t *testing.T) {
+	d, err := (&File{}).Open("file://relative/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := d.(*File).path; p != wd+"/relative/path" {
+		t.Fatalf("expected path relative to %s, got %s", wd, p)
+	}
+}
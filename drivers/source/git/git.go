@@ -0,0 +1,213 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	osexec "os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nrfta/ddsl/drivers/source"
+)
+
+func init() {
+	source.Register("git", &Git{})
+	source.Register("git+file", &Git{})
+}
+
+// Git reads files from a local git repository at a commit, tag or branch
+// without checking it out. Files are read with `git cat-file` when opened.
+type Git struct {
+	url    string
+	repo   string
+	prefix string
+	ref    string
+	commit string
+	files  []string
+}
+
+func Register() {
+	// do nothing, but call to force compiler to accept import without use
+}
+
+func (g *Git) Open(url string) (source.Driver, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// concat host and path to restore full path
+	// host might be `.`
+	p := u.Opaque
+	if len(p) == 0 {
+		p = u.Host + u.Path
+	}
+
+	if len(p) == 0 {
+		p = "."
+	}
+	if p, err = filepath.Abs(p); err != nil {
+		return nil, err
+	}
+
+	ref := u.Fragment
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+	// git would parse the ref as an option
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("git source driver: invalid ref '%s'", ref)
+	}
+
+	repo, prefix, err := findRepo(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ng := &Git{
+		url:    url,
+		repo:   repo,
+		prefix: prefix,
+		ref:    ref,
+	}
+
+	// fail early on an unknown ref, and read every file at the same commit even if the ref moves
+	out, err := ng.git("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("git source driver: unknown ref '%s'", ref)
+	}
+	ng.commit = strings.TrimSpace(string(out))
+
+	return ng, nil
+}
+
+func (g *Git) Close() error {
+	// nothing do to here
+	return nil
+}
+
+func (g *Git) ReadFiles(relativeDir string, fileNamePattern string) (files []*source.FileReader, err error) {
+	dr, err := g.readDirectory(relativeDir, fileNamePattern, false)
+	if err != nil {
+		return nil, err
+	}
+	return dr.FileReaders, nil
+}
+
+func (g *Git) ReadDirectories(relativeDir string, dirNamePattern string) (files []*source.DirectoryReader, err error) {
+	dr, err := g.readDirectory(relativeDir, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return source.MatchDirectories(dr.SubDirectories, dirNamePattern), nil
+}
+
+func (g *Git) ReadTree(relativeDir string, fileNamePattern string) (tree *source.DirectoryReader, err error) {
+	return g.readDirectory(relativeDir, fileNamePattern, true)
+}
+
+func (g *Git) readDirectory(relativeDir string, fileNamePattern string, recursive bool) (*source.DirectoryReader, error) {
+	var re *regexp.Regexp
+	if len(fileNamePattern) > 0 {
+		re = regexp.MustCompile(fileNamePattern)
+	}
+
+	files, err := g.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return source.MakeDirectoryReader(files, g.root(), relativeDir, re, recursive, g.openFile), nil
+}
+
+// root names files by the source directory at the ref, e.g. /path/to/repo/database@v1.2.0
+func (g *Git) root() string {
+	return path.Join(g.repo, g.prefix) + "@" + g.ref
+}
+
+func (g *Git) openFile(relativePath string) (io.ReadCloser, error) {
+	content, err := g.git("cat-file", "blob", g.commit+":"+path.Join(g.prefix, relativePath))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// listFiles returns the sorted paths, relative to the source directory, of every file at the ref
+func (g *Git) listFiles() ([]string, error) {
+	if g.files != nil {
+		return g.files, nil
+	}
+
+	args := []string{"ls-tree", "-r", "-z", "--name-only", "--full-tree", g.commit}
+	if g.prefix != "." {
+		args = append(args, "--", g.prefix)
+	}
+
+	out, err := g.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, f := range strings.Split(string(out), "\x00") {
+		if len(f) == 0 {
+			continue
+		}
+		if g.prefix != "." {
+			f = strings.TrimPrefix(f, g.prefix+"/")
+		}
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	g.files = files
+	return files, nil
+}
+
+func (g *Git) git(args ...string) ([]byte, error) {
+	cmd := osexec.Command("git", append([]string{"-C", g.repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("git source driver: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// findRepo returns the top level directory of the repository containing dirPath and the path of
+// dirPath relative to it. The directory need not exist in the working tree since it may only exist
+// at another ref.
+func findRepo(dirPath string) (repo string, prefix string, err error) {
+	notFound := fmt.Errorf("git source driver: %s is not in a git repository", dirPath)
+	tail := ""
+	for dir := dirPath; ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			out, err := osexec.Command("git", "-C", dir, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+			if err != nil {
+				return "", "", notFound
+			}
+			lines := strings.Split(string(out), "\n")
+			if len(lines) < 2 {
+				return "", "", notFound
+			}
+			return lines[0], path.Join(".", lines[1], tail), nil
+		}
+		if dir == filepath.Dir(dir) {
+			return "", "", notFound
+		}
+		tail = path.Join(filepath.Base(dir), tail)
+	}
+}
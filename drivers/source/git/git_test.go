@@ -0,0 +1,191 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"path"
+	"path/filepath"
+	"testing"
+
+	st "github.com/nrfta/ddsl/drivers/source/testing"
+)
+
+// makeRepo creates a repository with tag v1 containing foo_database/schemas/foo/schema.create.sql
+// and a later commit that changes it and adds foo_database/schemas/bar
+func makeRepo(t *testing.T) string {
+	repo, err := ioutil.TempDir("", "ddsl-git-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) {
+		cmd := osexec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	write := func(relativePath, content string) {
+		p := path.Join(repo, relativePath)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("foo_database/schemas/foo/schema.create.sql", "CREATE SCHEMA foo;")
+	write("foo_database/schemas/foo/schema.drop.sql", "DROP SCHEMA foo;")
+	run("add", "-A")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1")
+	write("foo_database/schemas/foo/schema.create.sql", "CREATE SCHEMA IF NOT EXISTS foo;")
+	write("foo_database/schemas/bar/schema.create.sql", "CREATE SCHEMA bar;")
+	run("add", "-A")
+	run("commit", "-q", "-m", "v2")
+
+	return repo
+}
+
+func open(t *testing.T, url string) *Git {
+	d, err := (&Git{}).Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(*Git)
+}
+
+func TestReadFilesAtRef(t *testing.T) {
+	repo := makeRepo(t)
+	defer os.RemoveAll(repo)
+
+	for ref, expected := range map[string]string{
+		"":   "CREATE SCHEMA IF NOT EXISTS foo;",
+		"v1": "CREATE SCHEMA foo;",
+	} {
+		url := "git://" + path.Join(repo, "foo_database")
+		if len(ref) > 0 {
+			url += "#" + ref
+		}
+		g := open(t, url)
+
+		files, err := g.ReadFiles("schemas/foo", `schema\.create\.sql`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("%s: expected 1 file, got %d", url, len(files))
+		}
+
+		r, err := files[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Fatalf("%s: expected %q, got %q", url, expected, content)
+		}
+
+		if err = g.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadDirectoriesAtRef(t *testing.T) {
+	repo := makeRepo(t)
+	defer os.RemoveAll(repo)
+
+	for ref, expected := range map[string][]string{
+		"HEAD": {"bar", "foo"},
+		"v1":   {"foo"},
+	} {
+		g := open(t, "git+file://"+path.Join(repo, "foo_database")+"#"+ref)
+		defer g.Close()
+
+		dirs, err := g.ReadDirectories("schemas", ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, d := range dirs {
+			names = append(names, filepath.Base(d.DirectoryPath))
+		}
+		if len(names) != len(expected) || names[0] != expected[0] {
+			t.Fatalf("%s: expected %v, got %v", ref, expected, names)
+		}
+	}
+}
+
+func TestReadTree(t *testing.T) {
+	repo := makeRepo(t)
+	defer os.RemoveAll(repo)
+
+	g := open(t, "git://"+path.Join(repo, "foo_database"))
+	defer g.Close()
+
+	tree, err := g.ReadTree("schemas", `.*\.sql`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.FileReaders) != 0 || len(tree.SubDirectories) != 2 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	if len(tree.SubDirectories[1].FileReaders) != 2 {
+		t.Fatalf("expected 2 files in foo, got %v", tree.SubDirectories[1].FileReaders)
+	}
+}
+
+func TestOpenUnknownRef(t *testing.T) {
+	repo := makeRepo(t)
+	defer os.RemoveAll(repo)
+
+	if _, err := (&Git{}).Open("git://" + path.Join(repo, "foo_database") + "#nope"); err == nil {
+		t.Fatal("expected error for unknown ref")
+	}
+}
+
+func TestOpenOptionRef(t *testing.T) {
+	repo := makeRepo(t)
+	defer os.RemoveAll(repo)
+
+	if _, err := (&Git{}).Open("git://" + path.Join(repo, "foo_database") + "#--output=" + path.Join(repo, "out")); err == nil {
+		t.Fatal("expected error for ref starting with -")
+	}
+	if _, err := os.Stat(path.Join(repo, "out")); !os.IsNotExist(err) {
+		t.Fatal("expected the ref not to be passed to git")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	repo, err := ioutil.TempDir("", "ddsl-git-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+
+	run := func(args ...string) {
+		cmd := osexec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+
+	// commit the fixture at tag fixture, then remove it from HEAD and the working tree
+	dbPath := st.WriteFixture(t, repo)
+	run("init", "-q")
+	run("add", "-A")
+	run("commit", "-q", "-m", "fixture")
+	run("tag", "fixture")
+	st.Test(t, "git://"+dbPath)
+
+	run("rm", "-q", "-r", st.DatabaseName)
+	run("commit", "-q", "-m", "remove fixture")
+	st.TestRef(t, "git://"+dbPath, "fixture")
+}
@@ -0,0 +1,142 @@
+package iofs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	nurl "net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nrfta/ddsl/drivers/source"
+)
+
+// IOFS reads files from an io/fs file system such as an embed.FS. It is not registered
+// by default; register an instance under a scheme of your choosing:
+//
+//	//go:embed db
+//	var db embed.FS
+//
+//	d, _ := iofs.WithInstance(db)
+//	source.Register("embed", d)
+//
+// and use a source URL such as embed://db/foo_database.
+type IOFS struct {
+	fsys fs.FS
+	dir  string
+}
+
+// WithInstance returns a driver that reads from fsys. Its Open method returns drivers
+// for directories of fsys.
+func WithInstance(fsys fs.FS) (source.Driver, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("iofs source driver: no file system")
+	}
+	return &IOFS{fsys: fsys, dir: "."}, nil
+}
+
+func (f *IOFS) Open(url string) (source.Driver, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(u.Fragment) > 0 {
+		return nil, fmt.Errorf("iofs source driver: refs are not supported")
+	}
+
+	// concat host and path to restore full path
+	p := u.Opaque
+	if len(p) == 0 {
+		p = u.Host + u.Path
+	}
+
+	// paths of an fs.FS are unrooted
+	dir := path.Clean("./" + strings.TrimPrefix(p, "/"))
+	if !fs.ValidPath(dir) {
+		return nil, fmt.Errorf("iofs source driver: invalid path %s", p)
+	}
+
+	return &IOFS{fsys: f.fsys, dir: path.Join(f.dir, dir)}, nil
+}
+
+func (f *IOFS) Close() error {
+	// nothing do to here
+	return nil
+}
+
+func (f *IOFS) readDirectory(relativeDir string, fileNamePattern string, recursive bool) (*source.DirectoryReader, error) {
+	dirPath := path.Join(f.dir, relativeDir)
+	var re *regexp.Regexp
+	if len(fileNamePattern) > 0 {
+		re = regexp.MustCompile(fileNamePattern)
+	}
+
+	dr := &source.DirectoryReader{
+		DirectoryPath:  dirPath,
+		FileReaders:    []*source.FileReader{},
+		SubDirectories: []*source.DirectoryReader{},
+	}
+
+	items, err := fs.ReadDir(f.fsys, dirPath)
+	if err != nil {
+		if _, statErr := fs.Stat(f.fsys, dirPath); statErr != nil {
+			return dr, nil
+		}
+		return nil, err
+	}
+
+	for _, item := range items {
+		itemPath := path.Join(dirPath, item.Name())
+		if item.IsDir() {
+			var subdr *source.DirectoryReader
+			if recursive {
+				if subdr, err = f.readDirectory(path.Join(relativeDir, item.Name()), fileNamePattern, recursive); err != nil {
+					return nil, err
+				}
+			} else {
+				subdr = &source.DirectoryReader{
+					DirectoryPath: itemPath,
+				}
+			}
+			dr.SubDirectories = append(dr.SubDirectories, subdr)
+		} else {
+			match := re == nil || re.MatchString(item.Name())
+			if !match {
+				continue
+			}
+
+			dr.FileReaders = append(dr.FileReaders, f.newFileReader(itemPath))
+		}
+	}
+
+	return dr, nil
+}
+
+func (f *IOFS) newFileReader(filePath string) *source.FileReader {
+	return &source.FileReader{
+		Name: filePath,
+		Open: func() (io.ReadCloser, error) { return f.fsys.Open(filePath) },
+	}
+}
+
+func (f *IOFS) ReadFiles(relativeDir string, fileNamePattern string) (files []*source.FileReader, err error) {
+	dr, err := f.readDirectory(relativeDir, fileNamePattern, false)
+	if err != nil {
+		return nil, err
+	}
+	return dr.FileReaders, nil
+}
+
+func (f *IOFS) ReadDirectories(relativeDir string, dirNamePattern string) (files []*source.DirectoryReader, err error) {
+	dr, err := f.readDirectory(relativeDir, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return source.MatchDirectories(dr.SubDirectories, dirNamePattern), nil
+}
+
+func (f *IOFS) ReadTree(relativeDir string, fileNamePattern string) (tree *source.DirectoryReader, err error) {
+	return f.readDirectory(relativeDir, fileNamePattern, true)
+}
@@ -0,0 +1,117 @@
+package iofs
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nrfta/ddsl/drivers/source"
+	st "github.com/nrfta/ddsl/drivers/source/testing"
+)
+
+var testFS = fstest.MapFS{
+	"db/foo_database/schemas/foo/schema.create.sql": {Data: []byte("CREATE SCHEMA foo;")},
+	"db/foo_database/schemas/foo/schema.drop.sql":   {Data: []byte("DROP SCHEMA foo;")},
+	"db/foo_database/schemas/bar/schema.create.sql": {Data: []byte("CREATE SCHEMA bar;")},
+}
+
+func open(t *testing.T, url string) source.Driver {
+	i, err := WithInstance(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := i.Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestReadFiles(t *testing.T) {
+	d := open(t, "embed://db/foo_database")
+	defer d.Close()
+
+	files, err := d.ReadFiles("schemas/foo", `schema\.create\.sql`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "db/foo_database/schemas/foo/schema.create.sql" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	r, err := files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "CREATE SCHEMA foo;" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	files, err = d.ReadFiles("nonexistent", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected no files, got %v", files)
+	}
+}
+
+func TestReadDirectories(t *testing.T) {
+	d := open(t, "embed:///db/foo_database/")
+	defer d.Close()
+
+	dirs, err := d.ReadDirectories("schemas", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || path.Base(dirs[0].DirectoryPath) != "bar" || path.Base(dirs[1].DirectoryPath) != "foo" {
+		t.Fatalf("unexpected directories %v", dirs)
+	}
+}
+
+func TestReadTree(t *testing.T) {
+	d := open(t, "embed://db/foo_database")
+	defer d.Close()
+
+	tree, err := d.ReadTree("schemas", `.*\.create\.sql`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.SubDirectories) != 2 || len(tree.SubDirectories[1].FileReaders) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	i, err := WithInstance(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = i.Open("embed://db/../foo#v1"); err == nil {
+		t.Fatal("expected error for ref")
+	}
+	if _, err = WithInstance(nil); err == nil {
+		t.Fatal("expected error for nil file system")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, relativePath := range st.Fixture {
+		fsys[path.Join("db", st.DatabaseName, relativePath)] = &fstest.MapFile{Data: []byte(st.Content(relativePath))}
+	}
+
+	d, err := WithInstance(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.Register("iofs-test", d)
+
+	st.Test(t, "iofs-test://db/"+st.DatabaseName)
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MakeDirectoryReader returns a `DirectoryReader` for the directory at the given relative path
+// from the sorted, slash separated relative paths of every file in a source. It is meant for
+// drivers that can list their files but have no directories to read. Returned paths are joined
+// to root to name them, and open is called with the relative path of a file to read it.
+func MakeDirectoryReader(files []string, root string, relativeDir string, re *regexp.Regexp, recursive bool,
+	open func(string) (io.ReadCloser, error)) *DirectoryReader {
+	dir := path.Clean(relativeDir)
+	dr := &DirectoryReader{
+		DirectoryPath:  path.Join(root, dir),
+		FileReaders:    []*FileReader{},
+		SubDirectories: []*DirectoryReader{},
+	}
+
+	subDirs := []string{}
+	for _, f := range files {
+		rel := f
+		if dir != "." {
+			if !strings.HasPrefix(f, dir+"/") {
+				continue
+			}
+			rel = f[len(dir)+1:]
+		}
+
+		i := strings.Index(rel, "/")
+		if i > -1 {
+			subDir := path.Join(dir, rel[:i])
+			if len(subDirs) == 0 || subDirs[len(subDirs)-1] != subDir {
+				subDirs = append(subDirs, subDir)
+			}
+			continue
+		}
+
+		if re != nil && !re.MatchString(rel) {
+			continue
+		}
+
+		relativePath := path.Join(dir, rel)
+		dr.FileReaders = append(dr.FileReaders, &FileReader{
+			Name: path.Join(root, relativePath),
+			Open: func() (io.ReadCloser, error) { return open(relativePath) },
+		})
+	}
+
+	for _, subDir := range subDirs {
+		if recursive {
+			dr.SubDirectories = append(dr.SubDirectories, MakeDirectoryReader(files, root, subDir, re, recursive, open))
+		} else {
+			dr.SubDirectories = append(dr.SubDirectories, &DirectoryReader{DirectoryPath: path.Join(root, subDir)})
+		}
+	}
+
+	return dr
+}
+
+// MatchDirectories returns the directories whose names match the pattern. An empty pattern
+// matches every directory.
+func MatchDirectories(dirs []*DirectoryReader, dirNamePattern string) []*DirectoryReader {
+	if len(dirNamePattern) == 0 {
+		return dirs
+	}
+
+	re := regexp.MustCompile(dirNamePattern)
+	result := []*DirectoryReader{}
+	for _, dr := range dirs {
+		if re.MatchString(path.Base(dr.DirectoryPath)) {
+			result = append(result, dr)
+		}
+	}
+	return result
+}
@@ -0,0 +1,202 @@
+package overlay
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nrfta/ddsl/drivers/source"
+)
+
+const scheme = "overlay"
+
+func init() {
+	source.Register(scheme, &Overlay{})
+}
+
+// Overlay stacks the database directories of several sources, e.g.
+// overlay:file:///shared/foo_database,git:///product/foo_database#v2
+//
+// Reading a directory returns the union of its contents in every layer. When the same file exists
+// in more than one layer, the file of the layer listed last wins, so later layers override earlier
+// ones. Files are named by the layer they were read from, which is also their origin.
+type Overlay struct {
+	layers []*layer
+}
+
+type layer struct {
+	url    string
+	driver source.Driver
+}
+
+func Register() {
+	// do nothing, but call to force compiler to accept import without use
+}
+
+// Layers returns the layer URLs of an overlay source URL, lowest precedence first, and false
+// if the URL is not an overlay URL.
+func Layers(url string) ([]string, bool) {
+	if !strings.HasPrefix(url, scheme+":") {
+		return nil, false
+	}
+
+	layers := []string{}
+	for _, l := range strings.Split(url[len(scheme)+1:], ",") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			layers = append(layers, l)
+		}
+	}
+	return layers, true
+}
+
+func (o *Overlay) Open(url string) (source.Driver, error) {
+	urls, ok := Layers(url)
+	if !ok {
+		return nil, fmt.Errorf("overlay source driver: invalid URL %s", url)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("overlay source driver: no layers")
+	}
+
+	no := &Overlay{}
+	for _, u := range urls {
+		d, err := source.Open(u)
+		if err != nil {
+			no.Close()
+			return nil, fmt.Errorf("overlay source driver: layer %s: %v", u, err)
+		}
+		no.layers = append(no.layers, &layer{url: u, driver: d})
+	}
+
+	return no, nil
+}
+
+func (o *Overlay) Close() error {
+	var firstErr error
+	for _, l := range o.layers {
+		if err := l.driver.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (o *Overlay) ReadFiles(relativeDir string, fileNamePattern string) (files []*source.FileReader, err error) {
+	byName := map[string]*source.FileReader{}
+	for _, l := range o.layers {
+		frs, err := l.driver.ReadFiles(relativeDir, fileNamePattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, fr := range frs {
+			byName[path.Base(fr.Name)] = l.withOrigin(fr)
+		}
+	}
+	return sortedFileReaders(byName), nil
+}
+
+func (o *Overlay) ReadDirectories(relativeDir string, dirNamePattern string) (files []*source.DirectoryReader, err error) {
+	byName := map[string]*source.DirectoryReader{}
+	for _, l := range o.layers {
+		drs, err := l.driver.ReadDirectories(relativeDir, dirNamePattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, dr := range drs {
+			byName[path.Base(dr.DirectoryPath)] = dr
+		}
+	}
+	return sortedDirectoryReaders(byName), nil
+}
+
+func (o *Overlay) ReadTree(relativeDir string, fileNamePattern string) (tree *source.DirectoryReader, err error) {
+	for _, l := range o.layers {
+		t, err := l.driver.ReadTree(relativeDir, fileNamePattern)
+		if err != nil {
+			return nil, err
+		}
+		l.setOrigin(t)
+		if tree == nil {
+			tree = t
+		} else {
+			tree = mergeTrees(tree, t)
+		}
+	}
+	return tree, nil
+}
+
+// withOrigin returns a file read from the layer, naming the layer as its origin unless the
+// layer's driver already did
+func (l *layer) withOrigin(fr *source.FileReader) *source.FileReader {
+	if len(fr.Origin) > 0 {
+		return fr
+	}
+	return &source.FileReader{Name: fr.Name, Open: fr.Open, Origin: l.url}
+}
+
+func (l *layer) setOrigin(tree *source.DirectoryReader) {
+	for i, fr := range tree.FileReaders {
+		tree.FileReaders[i] = l.withOrigin(fr)
+	}
+	for _, sub := range tree.SubDirectories {
+		l.setOrigin(sub)
+	}
+}
+
+// mergeTrees returns the union of two trees of the same directory where upper overrides lower
+func mergeTrees(lower, upper *source.DirectoryReader) *source.DirectoryReader {
+	files := map[string]*source.FileReader{}
+	for _, frs := range [][]*source.FileReader{lower.FileReaders, upper.FileReaders} {
+		for _, fr := range frs {
+			files[path.Base(fr.Name)] = fr
+		}
+	}
+
+	dirs := map[string]*source.DirectoryReader{}
+	for _, dr := range lower.SubDirectories {
+		dirs[path.Base(dr.DirectoryPath)] = dr
+	}
+	for _, dr := range upper.SubDirectories {
+		name := path.Base(dr.DirectoryPath)
+		if l, ok := dirs[name]; ok {
+			dirs[name] = mergeTrees(l, dr)
+		} else {
+			dirs[name] = dr
+		}
+	}
+
+	return &source.DirectoryReader{
+		DirectoryPath:  upper.DirectoryPath,
+		FileReaders:    sortedFileReaders(files),
+		SubDirectories: sortedDirectoryReaders(dirs),
+	}
+}
+
+func sortedFileReaders(byName map[string]*source.FileReader) []*source.FileReader {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	frs := []*source.FileReader{}
+	for _, name := range names {
+		frs = append(frs, byName[name])
+	}
+	return frs
+}
+
+func sortedDirectoryReaders(byName map[string]*source.DirectoryReader) []*source.DirectoryReader {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	drs := []*source.DirectoryReader{}
+	for _, name := range names {
+		drs = append(drs, byName[name])
+	}
+	return drs
+}
@@ -0,0 +1,166 @@
+package overlay
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nrfta/ddsl/drivers/source"
+	"github.com/nrfta/ddsl/drivers/source/iofs"
+	st "github.com/nrfta/ddsl/drivers/source/testing"
+)
+
+func init() {
+	for scheme, fsys := range map[string]fstest.MapFS{
+		"shared": {
+			"foo_database/schemas/audit/schema.create.sql": {Data: []byte("CREATE SCHEMA audit;")},
+			"foo_database/schemas/foo/schema.create.sql":   {Data: []byte("CREATE SCHEMA foo;")},
+			"foo_database/schemas/foo/schema.drop.sql":     {Data: []byte("DROP SCHEMA foo;")},
+		},
+		"product": {
+			"foo_database/schemas/bar/schema.create.sql": {Data: []byte("CREATE SCHEMA bar;")},
+			"foo_database/schemas/foo/schema.create.sql": {Data: []byte("CREATE SCHEMA IF NOT EXISTS foo;")},
+		},
+	} {
+		d, err := iofs.WithInstance(fsys)
+		if err != nil {
+			panic(err)
+		}
+		source.Register(scheme, d)
+	}
+}
+
+const url = "overlay:shared://foo_database,product://foo_database"
+
+func open(t *testing.T) source.Driver {
+	d, err := (&Overlay{}).Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func readString(t *testing.T, fr *source.FileReader) string {
+	r, err := fr.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestLayers(t *testing.T) {
+	layers, ok := Layers(url)
+	if !ok || len(layers) != 2 || layers[0] != "shared://foo_database" || layers[1] != "product://foo_database" {
+		t.Fatalf("unexpected layers %v", layers)
+	}
+	if _, ok = Layers("file:///foo_database"); ok {
+		t.Fatal("expected file URL not to be an overlay URL")
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	d := open(t)
+	defer d.Close()
+
+	files, err := d.ReadFiles("schemas/foo", `.*\.sql`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+
+	// the upper layer overrides the create script
+	if files[0].Origin != "product://foo_database" || readString(t, files[0]) != "CREATE SCHEMA IF NOT EXISTS foo;" {
+		t.Fatalf("unexpected file %+v", files[0])
+	}
+	if files[1].Origin != "shared://foo_database" || readString(t, files[1]) != "DROP SCHEMA foo;" {
+		t.Fatalf("unexpected file %+v", files[1])
+	}
+
+	files, err = d.ReadFiles("nonexistent", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected no files, got %v", files)
+	}
+}
+
+func TestReadDirectories(t *testing.T) {
+	d := open(t)
+	defer d.Close()
+
+	dirs, err := d.ReadDirectories("schemas", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, dr := range dirs {
+		names = append(names, path.Base(dr.DirectoryPath))
+	}
+	if len(names) != 3 || names[0] != "audit" || names[1] != "bar" || names[2] != "foo" {
+		t.Fatalf("unexpected directories %v", names)
+	}
+}
+
+func TestReadTree(t *testing.T) {
+	d := open(t)
+	defer d.Close()
+
+	tree, err := d.ReadTree("schemas", `.*\.create\.sql`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.SubDirectories) != 3 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+
+	foo := tree.SubDirectories[2]
+	if len(foo.FileReaders) != 1 || foo.FileReaders[0].Origin != "product://foo_database" {
+		t.Fatalf("unexpected files in foo %+v", foo.FileReaders)
+	}
+	audit := tree.SubDirectories[0]
+	if len(audit.FileReaders) != 1 || audit.FileReaders[0].Origin != "shared://foo_database" {
+		t.Fatalf("unexpected files in audit %+v", audit.FileReaders)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	for _, u := range []string{"overlay:", "overlay:shared://foo_database,nope://foo_database"} {
+		if _, err := (&Overlay{}).Open(u); err == nil {
+			t.Fatalf("%s: expected error", u)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	// split the fixture across two layers, with stale copies of some files in the lower one
+	lower, upper := fstest.MapFS{}, fstest.MapFS{}
+	for i, relativePath := range st.Fixture {
+		p := path.Join(st.DatabaseName, relativePath)
+		if i%2 == 0 {
+			lower[p] = &fstest.MapFile{Data: []byte(st.Content(relativePath))}
+		} else {
+			lower[p] = &fstest.MapFile{Data: []byte("stale")}
+			upper[p] = &fstest.MapFile{Data: []byte(st.Content(relativePath))}
+		}
+	}
+
+	for scheme, fsys := range map[string]fstest.MapFS{"lower": lower, "upper": upper} {
+		d, err := iofs.WithInstance(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		source.Register(scheme, d)
+	}
+
+	st.Test(t, "overlay:lower://"+st.DatabaseName+",upper://"+st.DatabaseName)
+}
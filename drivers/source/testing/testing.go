@@ -0,0 +1,245 @@
+// Package testing has the source driver tests.
+// All source drivers must pass the Test function.
+// This lives in it's own package so it stays a test dependency.
+package testing
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nrfta/ddsl/drivers/source"
+)
+
+// DatabaseName is the name of the database directory of the fixture repository
+const DatabaseName = "foo_database"
+
+// Fixture is the repository tree drivers are tested against. Paths are relative to the database
+// directory and each file contains its own path, see Content.
+var Fixture = []string{
+	"database.create.sql",
+	"database.drop.sql",
+	"schemas/bar/schema.create.sql",
+	"schemas/foo/schema.create.sql",
+	"schemas/foo/schema.drop.sql",
+	"schemas/foo/tables/bar.create.sql",
+	"schemas/foo/tables/bar.drop.sql",
+	"schemas/foo/tables/baz.create.sql",
+	"schemas/foo/tables/baz/seeds/data.csv",
+}
+
+// Content returns the content of a fixture file
+func Content(relativePath string) string {
+	return "-- " + relativePath + "\n"
+}
+
+// WriteFixture writes the fixture repository to dir and returns the path of its database directory
+func WriteFixture(t *testing.T, dir string) string {
+	dbPath := path.Join(dir, DatabaseName)
+	for _, relativePath := range Fixture {
+		p := path.Join(dbPath, relativePath)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(Content(relativePath)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dbPath
+}
+
+// Test runs tests against source driver implementations. The URL must point at the database
+// directory of the fixture repository, see WriteFixture.
+func Test(t *testing.T, url string) {
+	d, err := source.Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	TestReadFiles(t, d)
+	TestReadDirectories(t, d)
+	TestReadTree(t, d)
+	TestNonexistentDirectory(t, d)
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	TestUnknownRef(t, url)
+}
+
+// TestRef runs the tests of Test against drivers supporting refs. The database directory of the
+// fixture repository must exist at the ref, which is appended to the URL as a fragment.
+func TestRef(t *testing.T, url string, ref string) {
+	if i := strings.Index(url, "#"); i > -1 {
+		url = url[:i]
+	}
+	Test(t, url+"#"+ref)
+}
+
+func TestReadFiles(t *testing.T, d source.Driver) {
+	for _, tc := range []struct {
+		relativeDir string
+		pattern     string
+		expected    []string
+	}{
+		{".", `database\.create\.sql`, []string{"database.create.sql"}},
+		{".", `.*`, []string{"database.create.sql", "database.drop.sql"}},
+		{"schemas/foo", `schema\.drop\.sql`, []string{"schemas/foo/schema.drop.sql"}},
+		{"schemas/foo/tables", `.*\.create\.sql`, []string{"schemas/foo/tables/bar.create.sql", "schemas/foo/tables/baz.create.sql"}},
+		{"schemas/foo/tables", `^bar\..*`, []string{"schemas/foo/tables/bar.create.sql", "schemas/foo/tables/bar.drop.sql"}},
+		{"schemas/foo/tables/", ``, []string{"schemas/foo/tables/bar.create.sql", "schemas/foo/tables/bar.drop.sql", "schemas/foo/tables/baz.create.sql"}},
+		{"schemas/foo/tables", `.*\.ddsl`, []string{}},
+	} {
+		files, err := d.ReadFiles(tc.relativeDir, tc.pattern)
+		if err != nil {
+			t.Fatalf("ReadFiles(%q, %q): %v", tc.relativeDir, tc.pattern, err)
+		}
+		expectFiles(t, "ReadFiles("+tc.relativeDir+", "+tc.pattern+")", files, tc.expected)
+	}
+}
+
+func TestReadDirectories(t *testing.T, d source.Driver) {
+	for _, tc := range []struct {
+		relativeDir string
+		pattern     string
+		expected    []string
+	}{
+		{".", `.*`, []string{"schemas"}},
+		{"schemas", `.*`, []string{"bar", "foo"}},
+		{"schemas", `^f`, []string{"foo"}},
+		{"schemas", ``, []string{"bar", "foo"}},
+		{"schemas/foo/tables", `.*`, []string{"baz"}},
+		{"schemas/bar", `.*`, []string{}},
+	} {
+		dirs, err := d.ReadDirectories(tc.relativeDir, tc.pattern)
+		if err != nil {
+			t.Fatalf("ReadDirectories(%q, %q): %v", tc.relativeDir, tc.pattern, err)
+		}
+		expectDirectories(t, "ReadDirectories("+tc.relativeDir+", "+tc.pattern+")", dirs, tc.expected)
+	}
+}
+
+func TestReadTree(t *testing.T, d source.Driver) {
+	tree, err := d.ReadTree("schemas", `.*\.create\.sql`)
+	if err != nil {
+		t.Fatalf("ReadTree: %v", err)
+	}
+	if tree == nil {
+		t.Fatal("ReadTree: expected a tree")
+	}
+	if !strings.HasSuffix(tree.DirectoryPath, "schemas") {
+		t.Fatalf("ReadTree: unexpected directory %s", tree.DirectoryPath)
+	}
+
+	expectFiles(t, "ReadTree schemas", tree.FileReaders, []string{})
+	expectDirectories(t, "ReadTree schemas", tree.SubDirectories, []string{"bar", "foo"})
+
+	bar, foo := tree.SubDirectories[0], tree.SubDirectories[1]
+	expectFiles(t, "ReadTree schemas/bar", bar.FileReaders, []string{"schemas/bar/schema.create.sql"})
+	expectDirectories(t, "ReadTree schemas/bar", bar.SubDirectories, []string{})
+	expectFiles(t, "ReadTree schemas/foo", foo.FileReaders, []string{"schemas/foo/schema.create.sql"})
+	expectDirectories(t, "ReadTree schemas/foo", foo.SubDirectories, []string{"tables"})
+
+	tables := foo.SubDirectories[0]
+	expectFiles(t, "ReadTree schemas/foo/tables", tables.FileReaders,
+		[]string{"schemas/foo/tables/bar.create.sql", "schemas/foo/tables/baz.create.sql"})
+	expectDirectories(t, "ReadTree schemas/foo/tables", tables.SubDirectories, []string{"baz"})
+
+	// the pattern applies to files at every level, so seeds/data.csv is not returned
+	baz := tables.SubDirectories[0]
+	expectDirectories(t, "ReadTree schemas/foo/tables/baz", baz.SubDirectories, []string{"seeds"})
+	expectFiles(t, "ReadTree schemas/foo/tables/baz/seeds", baz.SubDirectories[0].FileReaders, []string{})
+}
+
+func TestNonexistentDirectory(t *testing.T, d source.Driver) {
+	files, err := d.ReadFiles("nonexistent", ".*")
+	if err != nil {
+		t.Fatalf("ReadFiles: %v", err)
+	}
+	expectFiles(t, "ReadFiles nonexistent", files, []string{})
+
+	dirs, err := d.ReadDirectories("schemas/nonexistent", ".*")
+	if err != nil {
+		t.Fatalf("ReadDirectories: %v", err)
+	}
+	expectDirectories(t, "ReadDirectories nonexistent", dirs, []string{})
+
+	tree, err := d.ReadTree("nonexistent", ".*")
+	if err != nil {
+		t.Fatalf("ReadTree: %v", err)
+	}
+	if tree == nil {
+		t.Fatal("ReadTree nonexistent: expected an empty tree, got nil")
+	}
+	expectFiles(t, "ReadTree nonexistent", tree.FileReaders, []string{})
+	expectDirectories(t, "ReadTree nonexistent", tree.SubDirectories, []string{})
+}
+
+// TestUnknownRef checks that a driver fails to open a ref that does not exist rather than
+// returning no files. Drivers without refs must reject them.
+func TestUnknownRef(t *testing.T, url string) {
+	if i := strings.Index(url, "#"); i > -1 {
+		url = url[:i]
+	}
+
+	d, err := source.Open(url + "#ddsl-nonexistent-ref")
+	if err == nil {
+		d.Close()
+		t.Fatal("expected error opening an unknown ref")
+	}
+}
+
+// expectFiles checks that files are the expected fixture files, in order, by name and content
+func expectFiles(t *testing.T, desc string, files []*source.FileReader, expected []string) {
+	if len(files) != len(expected) {
+		t.Fatalf("%s: expected %d files, got %d: %s", desc, len(expected), len(files), fileNames(files))
+	}
+
+	for i, fr := range files {
+		if !strings.HasSuffix(fr.Name, "/"+expected[i]) {
+			t.Fatalf("%s: expected %s, got %s", desc, expected[i], fr.Name)
+		}
+
+		r, err := fr.Open()
+		if err != nil {
+			t.Fatalf("%s: open %s: %v", desc, fr.Name, err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: read %s: %v", desc, fr.Name, err)
+		}
+		if string(content) != Content(expected[i]) {
+			t.Fatalf("%s: unexpected content of %s: %q", desc, fr.Name, content)
+		}
+	}
+}
+
+// expectDirectories checks that dirs have the expected names, in order
+func expectDirectories(t *testing.T, desc string, dirs []*source.DirectoryReader, expected []string) {
+	names := []string{}
+	for _, dr := range dirs {
+		names = append(names, path.Base(dr.DirectoryPath))
+	}
+
+	if len(names) != len(expected) || !sort.StringsAreSorted(names) {
+		t.Fatalf("%s: expected directories %v, got %v", desc, expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("%s: expected directories %v, got %v", desc, expected, names)
+		}
+	}
+}
+
+func fileNames(files []*source.FileReader) []string {
+	names := []string{}
+	for _, fr := range files {
+		names = append(names, fr.Name)
+	}
+	return names
+}
@@ -1,7 +1,6 @@
 package exec
 
 import (
-	"fmt"
 	"os/user"
 	"strings"
 )
@@ -9,10 +8,10 @@ import (
 func ensureAuditTable(ctx *Context) error {
 	sql := `
 	CREATE TABLE IF NOT EXISTS ddsl_audit (
-		ddsl_command CHARACTER VARYING,
-		performed_at TIMESTAMP WITHOUT TIME ZONE,
-		by_db_user CHARACTER VARYING,
-		by_os_user CHARACTER VARYING
+		ddsl_command TEXT,
+		performed_at TIMESTAMP,
+		by_db_user TEXT,
+		by_os_user TEXT
 	)`
 	return ctx.dbDriver.Exec(strings.NewReader(sql))
 }
@@ -20,14 +19,11 @@ func ensureAuditTable(ctx *Context) error {
 func (p *processor) audit(ddslCommand string) error {
 	sql := `
 	INSERT INTO ddsl_audit (ddsl_command, performed_at, by_db_user, by_os_user)
-	VALUES ('%s', NOW(), '%s', '%s');
-	`
+	VALUES (?, CURRENT_TIMESTAMP, ?, ?)`
 	osUser, err := user.Current()
 	if err != nil {
 		return err
 	}
 
-	sql = fmt.Sprintf(sql, ddslCommand, p.ctx.dbDriver.User(), osUser.Username)
-	return p.ctx.dbDriver.Exec(strings.NewReader(sql))
-
+	return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), ddslCommand, p.ctx.dbDriver.User(), osUser.Username)
 }
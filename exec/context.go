@@ -1,7 +1,10 @@
 package exec
 
 import (
+	"fmt"
 	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/drivers/source"
+	"io"
 	"strings"
 )
 
@@ -11,16 +14,24 @@ type Context struct {
 	AutoTransaction bool
 	DryRun          bool
 	OutputFormat    string
+	IgnoreChecksums bool
+	// MigrationVersionScheme is timestamp (default) or sequential
+	MigrationVersionScheme string
+	// OutOfOrder is the policy for pending migrations older than the current version:
+	// reject (default), warn or apply
+	OutOfOrder string
+	// Plan lists the instructions of a batch instead of processing them
+	Plan            bool
 	inTransaction   bool
 	dbDriver        dbdr.Driver
 	patterns        []string
 	instructions    []*instruction
 	nesting         int
 	nonList         bool
-}
-
-type Name struct {
-	Name *string
+	migrationLedger []*ledgerEntry
+	sourceDrivers   map[string]source.Driver
+	files           map[string]*source.FileReader
+	relativePaths   map[string]string
 }
 
 func NewContext(sourceRepo, databaseURL string, autoTx, dryRun bool, output_format string) *Context {
@@ -37,48 +48,56 @@ func NewContext(sourceRepo, databaseURL string, autoTx, dryRun bool, output_form
 }
 
 func (c *Context) GetDatabaseSchemas() ([]string, error) {
-	return c.getNames("SELECT schema_name AS name FROM information_schema.schemata;")
+	dbDriver, err := dbdr.Open(c.DatbaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer dbDriver.Close()
+
+	return dbDriver.Schemas()
 }
 func (c *Context) GetDatabaseTables() ([]string, error) {
-	return c.getNames(`SELECT schema_name + '.' + table_name AS name
-                             FROM information_schema.tables
-                             WHERE table_type = 'BASE TABLE'`)
+	return c.getSchemaItemNames(dbdr.Driver.Tables)
 }
 func (c *Context) GetDatabaseViews() ([]string, error) {
-	return c.getNames(`SELECT schema_name + '.' + table_name AS name
-                             FROM information_schema.tables
-                             WHERE table_type = 'VIEW'`)
+	return c.getSchemaItemNames(dbdr.Driver.Views)
 }
 func (c *Context) GetDatabaseTypes() ([]string, error) {
-	return c.getNames(`SELECT udt_schema + '.' + udt.name AS name
-                             FROM information_schema.columns
-                             WHERE data_type = 'USER-DEFINED'`)
+	return c.getSchemaItemNames(dbdr.Driver.Types)
 }
 
-func (c *Context) getNames(query string) ([]string, error) {
-	dbdr, err := c.dbDriver.Open(c.DatbaseUrl)
+// getSchemaItemNames returns the qualified names of the items of every schema
+func (c *Context) getSchemaItemNames(schemaItems func(dbdr.Driver, string) ([]*dbdr.SchemaItemInfo, error)) ([]string, error) {
+	dbDriver, err := dbdr.Open(c.DatbaseUrl)
 	if err != nil {
 		return nil, err
 	}
+	defer dbDriver.Close()
 
-	rows, err := dbdr.Query(strings.NewReader(query))
+	schemas, err := dbDriver.Schemas()
 	if err != nil {
 		return nil, err
 	}
 
 	names := []string{}
-	for rows.Next() {
-		n := &Name{}
-		err := rows.Scan(n)
+	for _, schema := range schemas {
+		items, err := schemaItems(dbDriver, schema)
 		if err != nil {
 			return nil, err
 		}
-		names = append(names, *n.Name)
+		for _, item := range items {
+			names = append(names, item.SchemaName+"."+item.ItemName)
+		}
 	}
 
 	return names, nil
 }
 
+// bind replaces the ? placeholders of a bookkeeping statement with those of the database
+func (c *Context) bind(sql string) string {
+	return c.dbDriver.Dialect().Bind(sql)
+}
+
 func (c *Context) addPattern(pattern string) {
 	c.patterns = append(c.patterns, pattern)
 }
@@ -119,6 +138,75 @@ func (c *Context) resetNesting() {
 
 func (c *Context) clearInstructions() {
 	c.instructions = []*instruction{}
+	c.nonList = false
+}
+
+func (c *Context) clearMigrationLedger() {
+	c.migrationLedger = nil
+}
+
+// openSourceDriver returns the source driver for a URL, opening it once per batch. Drivers stay
+// open until the batch has been processed because files they return may only exist while open.
+func (c *Context) openSourceDriver(url string) (source.Driver, error) {
+	if sourceDriver, ok := c.sourceDrivers[url]; ok {
+		return sourceDriver, nil
+	}
+
+	sourceDriver, err := source.Open(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.sourceDrivers == nil {
+		c.sourceDrivers = map[string]source.Driver{}
+	}
+	c.sourceDrivers[url] = sourceDriver
+	return sourceDriver, nil
+}
+
+func (c *Context) closeSourceDrivers() {
+	for _, sourceDriver := range c.sourceDrivers {
+		sourceDriver.Close()
+	}
+	c.sourceDrivers = nil
+	c.files = nil
+	c.relativePaths = nil
+}
+
+// addFile remembers a file read from a source driver so the processor can open it by name
+func (c *Context) addFile(fr *source.FileReader, relativePath string) {
+	if c.files == nil {
+		c.files = map[string]*source.FileReader{}
+		c.relativePaths = map[string]string{}
+	}
+	c.files[fr.Name] = fr
+	c.relativePaths[fr.Name] = relativePath
+}
+
+// relativePath returns the path of a file read in this batch relative to its source repo
+func (c *Context) relativePath(name string) string {
+	if relativePath, ok := c.relativePaths[name]; ok {
+		return relativePath
+	}
+	return name
+}
+
+// openFile opens a file read from a source driver in this batch by name
+func (c *Context) openFile(name string) (io.ReadCloser, error) {
+	fr, ok := c.files[name]
+	if !ok {
+		return nil, fmt.Errorf("file %s was not read from the source repo", name)
+	}
+	return fr.Open()
+}
+
+// describeFile returns the name of a file read in this batch followed by the source it came
+// from when its driver combines several sources
+func (c *Context) describeFile(name string) string {
+	if fr, ok := c.files[name]; ok && len(fr.Origin) > 0 {
+		return fmt.Sprintf("%s (from %s)", name, fr.Origin)
+	}
+	return name
 }
 
 func (c *Context) addInstructionWithParams(instrType InstructionType, params map[string]interface{}) {
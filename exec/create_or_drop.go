@@ -67,16 +67,7 @@ func (p *preprocessor) preprocessTypes() (int, error) {
 		return 0, err
 	}
 
-	count := 0
-	for _, schemaName := range schemaNames {
-		c, err := p.preprocessCreateOrDropKey(TYPES, schemaName)
-		count += c
-		if err != nil {
-			return count, err
-		}
-	}
-
-	return count, nil
+	return p.preprocessOrderedSchemaItems(TYPES, schemaNames)
 }
 
 func (p *preprocessor) preprocessForeignKeys() (int, error) {
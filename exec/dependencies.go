@@ -0,0 +1,246 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nrfta/ddsl/drivers/source"
+	"github.com/nrfta/ddsl/log"
+)
+
+// DEPENDS_ON_FILE is the manifest in the directory of a view, function or procedure listing the
+// items of the same type it depends on. A type's manifest is <type_name>.depends-on.txt.
+const DEPENDS_ON_FILE = "depends-on.txt"
+
+// dependencyOrderedKeys are the pathPatterns keys of the schema items ordered by their dependencies
+var dependencyOrderedKeys = map[string]bool{
+	VIEWS:      true,
+	FUNCTIONS:  true,
+	PROCEDURES: true,
+	TYPES:      true,
+}
+
+// dependentItem is a schema item file and the items it depends on
+type dependentItem struct {
+	name      string
+	file      *source.FileReader
+	dependsOn []string
+}
+
+// preprocessOrderedSchemaItems adds the instructions to create or drop the items of a type in the
+// schemas so that items are created after and dropped before the items they depend on. Items
+// without dependencies keep their schema and name order. When creating, the attachments of a
+// schema follow its last item.
+func (p *preprocessor) preprocessOrderedSchemaItems(itemType string, schemaNames []string) (int, error) {
+	items := []*dependentItem{}
+	for _, schemaName := range schemaNames {
+		schemaItems, err := p.getDependentItems(itemType, schemaName)
+		if err != nil {
+			return 0, err
+		}
+		items = append(items, schemaItems...)
+	}
+
+	ordered, err := orderByDependencies(items, p.createOrDrop == DROP)
+	if err != nil {
+		return 0, fmt.Errorf("cannot order %s: %s", itemType, err)
+	}
+
+	last := map[string]int{}
+	for i, item := range ordered {
+		last[schemaOfItem(item.name)] = i
+	}
+
+	count := 0
+	for i, item := range ordered {
+		log.Debug("preprocessing %s", p.ctx.describeFile(item.file.Name))
+		p.ctx.addInstructionWithParams(INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: item.file.Name})
+		count++
+
+		schemaName := schemaOfItem(item.name)
+		if p.createOrDrop == CREATE && itemType != TYPES && last[schemaName] == i {
+			c, err := p.preprocessSchemaItemAttachments(itemType, schemaName)
+			count += c
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// getDependentItems returns the create or drop files of the items of a type in a schema with the
+// dependencies declared by their manifests
+func (p *preprocessor) getDependentItems(itemType, schemaName string) ([]*dependentItem, error) {
+	if err := p.ensureSourceDriverOpen(); err != nil {
+		return nil, err
+	}
+
+	relativePath, filePattern := path.Split(fmt.Sprintf(pathPatterns[itemType], schemaName, p.createOrDrop))
+	dirs, err := p.resolveDirectoryWildcards(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*dependentItem{}
+	for _, d := range dirs {
+		p.ctx.addPattern(path.Join(d, filePattern))
+
+		fileReaders, err := p.readFiles(d, filePattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fr := range fileReaders {
+			// types are files in the types directory, other items are directories
+			itemName, manifest := path.Base(d), DEPENDS_ON_FILE
+			if itemType == TYPES {
+				itemName = strings.TrimSuffix(path.Base(fr.Name), fmt.Sprintf(".%s.sql", p.createOrDrop))
+				manifest = itemName + "." + DEPENDS_ON_FILE
+			}
+
+			dependsOn, err := p.readDependencies(d, manifest)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, &dependentItem{name: schemaName + "." + itemName, file: fr, dependsOn: dependsOn})
+		}
+	}
+	return items, nil
+}
+
+// readDependencies returns the <schema_name>.<item_name> lines of a manifest. Blank lines and
+// lines starting with # are ignored.
+func (p *preprocessor) readDependencies(relativeDir, manifest string) ([]string, error) {
+	fileReaders, err := p.readFiles(relativeDir, "^"+regexp.QuoteMeta(manifest)+"$")
+	if err != nil || len(fileReaders) == 0 {
+		return nil, err
+	}
+
+	content, err := readAll(fileReaders[0])
+	if err != nil {
+		return nil, err
+	}
+
+	dependsOn := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, err := parseSchemaItemName(line); err != nil {
+			return nil, fmt.Errorf("%s: %s", path.Join(relativeDir, manifest), err)
+		}
+		dependsOn = append(dependsOn, line)
+	}
+	return dependsOn, scanner.Err()
+}
+
+// orderByDependencies orders items after the items they depend on or, when dropping, before
+// them. Of the items that are ready, the first in the given order goes next. Dependencies on
+// items not being ordered are left to the user, as they belong to another command.
+func orderByDependencies(items []*dependentItem, drop bool) ([]*dependentItem, error) {
+	byName := map[string]*dependentItem{}
+	for _, item := range items {
+		byName[item.name] = item
+	}
+
+	// waitsFor maps each item to the items that must precede it
+	waitsFor := map[*dependentItem][]*dependentItem{}
+	for _, item := range items {
+		for _, name := range item.dependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				log.Debug("%s depends on %s, which is not part of this command", item.name, name)
+				continue
+			}
+			if drop {
+				waitsFor[dependency] = append(waitsFor[dependency], item)
+			} else {
+				waitsFor[item] = append(waitsFor[item], dependency)
+			}
+		}
+	}
+
+	ordered := []*dependentItem{}
+	done := map[*dependentItem]bool{}
+	for len(ordered) < len(items) {
+		var next *dependentItem
+		for _, item := range items {
+			if !done[item] && allDone(waitsFor[item], done) {
+				next = item
+				break
+			}
+		}
+
+		if next == nil {
+			cycle := findCycle(items, waitsFor, done)
+			if drop {
+				// report the cycle as a chain of dependencies either way
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle %s", strings.Join(cycle, " -> "))
+		}
+
+		ordered = append(ordered, next)
+		done[next] = true
+	}
+	return ordered, nil
+}
+
+func allDone(items []*dependentItem, done map[*dependentItem]bool) bool {
+	for _, item := range items {
+		if !done[item] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCycle follows the items that must precede the first pending item until one repeats, and
+// returns the names of the items in the cycle
+func findCycle(items []*dependentItem, waitsFor map[*dependentItem][]*dependentItem, done map[*dependentItem]bool) []string {
+	var item *dependentItem
+	for _, i := range items {
+		if !done[i] {
+			item = i
+			break
+		}
+	}
+
+	chain := []*dependentItem{}
+	seen := map[*dependentItem]int{}
+	for {
+		if i, ok := seen[item]; ok {
+			chain = append(chain[i:], item)
+			break
+		}
+		seen[item] = len(chain)
+		chain = append(chain, item)
+
+		for _, w := range waitsFor[item] {
+			if !done[w] {
+				item = w
+				break
+			}
+		}
+	}
+
+	names := []string{}
+	for _, i := range chain {
+		names = append(names, i.name)
+	}
+	return names
+}
+
+func schemaOfItem(name string) string {
+	return name[:strings.Index(name, ".")]
+}
@@ -0,0 +1,121 @@
+package exec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("dependencies.go", func() {
+
+	var dir string
+
+	preprocess := func(command string) ([]string, error) {
+		ctx := &Context{SourceRepo: "file://" + dir}
+		cmds, _, _, err := parser.Parse(command)
+		Expect(err).To(BeNil())
+		if _, err = preprocessBatch(ctx, cmds); err != nil {
+			return nil, err
+		}
+
+		files := []string{}
+		for _, instr := range ctx.instructions[1:] {
+			rel, err := filepath.Rel(dir, instr.params[FILE_PATH].(string))
+			Expect(err).To(BeNil())
+			files = append(files, rel)
+		}
+		return files, nil
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "ddsl")
+		Expect(err).To(BeNil())
+
+		writeFiles(dir, map[string]string{
+			"schemas/a_schema/views/a_view/view.create.sql":      "",
+			"schemas/a_schema/views/a_view/view.drop.sql":        "",
+			"schemas/a_schema/views/a_view/depends-on.txt":       "# selects from b_view\nb_schema.b_view\n\na_schema.b_view\n",
+			"schemas/a_schema/views/a_view/privileges.grant.sql": "",
+			"schemas/a_schema/views/b_view/view.create.sql":      "",
+			"schemas/a_schema/views/b_view/view.drop.sql":        "",
+			"schemas/a_schema/views/b_view/depends-on.txt":       "a_schema.a_table\n",
+			"schemas/b_schema/views/b_view/view.create.sql":      "",
+			"schemas/b_schema/views/b_view/view.drop.sql":        "",
+			"schemas/b_schema/views/b_view/privileges.grant.sql": "",
+			"schemas/a_schema/types/a_type.create.sql":           "",
+			"schemas/a_schema/types/a_type.depends-on.txt":       "a_schema.b_type\n",
+			"schemas/a_schema/types/b_type.create.sql":           "",
+			"schemas/a_schema/types/c_type.create.sql":           "",
+		})
+	})
+
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	ginkgo.It("creates items after their dependencies", func() {
+		files, err := preprocess("create views")
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{
+			"schemas/a_schema/views/b_view/view.create.sql",
+			"schemas/b_schema/views/b_view/view.create.sql",
+			"schemas/b_schema/views/b_view/privileges.grant.sql",
+			"schemas/a_schema/views/a_view/view.create.sql",
+			"schemas/a_schema/views/a_view/privileges.grant.sql",
+		}))
+
+		files, err = preprocess("create types")
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{
+			"schemas/a_schema/types/b_type.create.sql",
+			"schemas/a_schema/types/a_type.create.sql",
+			"schemas/a_schema/types/c_type.create.sql",
+		}))
+	})
+
+	ginkgo.It("drops items before their dependencies", func() {
+		files, err := preprocess("drop views")
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{
+			"schemas/a_schema/views/a_view/view.drop.sql",
+			"schemas/a_schema/views/b_view/view.drop.sql",
+			"schemas/b_schema/views/b_view/view.drop.sql",
+		}))
+	})
+
+	ginkgo.It("ignores dependencies outside of the command", func() {
+		files, err := preprocess("create views in a_schema")
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{
+			"schemas/a_schema/views/b_view/view.create.sql",
+			"schemas/a_schema/views/a_view/view.create.sql",
+			"schemas/a_schema/views/a_view/privileges.grant.sql",
+		}))
+	})
+
+	ginkgo.It("reports dependency cycles", func() {
+		writeFiles(dir, map[string]string{
+			"schemas/b_schema/views/b_view/depends-on.txt": "a_schema.a_view\n",
+		})
+
+		_, err := preprocess("create views")
+		Expect(err).To(MatchError("cannot order views: dependency cycle a_schema.a_view -> b_schema.b_view -> a_schema.a_view"))
+
+		_, err = preprocess("drop views")
+		Expect(err).To(MatchError("cannot order views: dependency cycle a_schema.a_view -> b_schema.b_view -> a_schema.a_view"))
+	})
+
+	ginkgo.It("requires qualified dependencies", func() {
+		writeFiles(dir, map[string]string{
+			"schemas/a_schema/types/c_type.depends-on.txt": "b_type\n",
+		})
+
+		_, err := preprocess("create types")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,281 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/forestgiant/sliceutil"
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+)
+
+const (
+	// diff statuses
+	DIFF_MISSING = "missing"
+	DIFF_EXTRA   = "extra"
+	DIFF_PRESENT = "present"
+
+	// schemaItemTypeSchema is the item type of schemas in diff output
+	schemaItemTypeSchema = "SCHEMA"
+)
+
+// diffItemTypes are the schema item types compared by each diff command
+var diffItemTypes = map[string][]string{
+	SCHEMA_ITEMS: {
+		dbdr.SchemaItemTypeTable,
+		dbdr.SchemaItemTypeView,
+		dbdr.SchemaItemTypeFunction,
+		dbdr.SchemaItemTypeProcedure,
+		dbdr.SchemaItemTypeType,
+	},
+	TABLES:     {dbdr.SchemaItemTypeTable},
+	VIEWS:      {dbdr.SchemaItemTypeView},
+	FUNCTIONS:  {dbdr.SchemaItemTypeFunction},
+	PROCEDURES: {dbdr.SchemaItemTypeProcedure},
+	TYPES:      {dbdr.SchemaItemTypeType},
+}
+
+// diffSourceDirs are the directories of a schema holding a directory per item of a type
+var diffSourceDirs = map[string]string{
+	dbdr.SchemaItemTypeTable:     "schemas/%s/tables",
+	dbdr.SchemaItemTypeView:      "schemas/%s/views",
+	dbdr.SchemaItemTypeFunction:  "schemas/%s/functions",
+	dbdr.SchemaItemTypeProcedure: "schemas/%s/procedures",
+}
+
+// diffDatabaseItems returns the items of a type in a database schema
+var diffDatabaseItems = map[string]func(dbdr.Driver, string) ([]*dbdr.SchemaItemInfo, error){
+	dbdr.SchemaItemTypeTable:     dbdr.Driver.Tables,
+	dbdr.SchemaItemTypeView:      dbdr.Driver.Views,
+	dbdr.SchemaItemTypeFunction:  dbdr.Driver.Functions,
+	dbdr.SchemaItemTypeProcedure: dbdr.Driver.Procedures,
+	dbdr.SchemaItemTypeType:      dbdr.Driver.Types,
+}
+
+// bookkeepingTables are created by ddsl rather than declared in the source repo
+var bookkeepingTables = []string{"ddsl_audit", "ddsl_migrations"}
+
+func (p *preprocessor) preprocessDiff() (int, error) {
+	itemType := p.command.CommandDef.Name
+	if itemType == SCHEMAS {
+		return p.preprocessDiffSchemas()
+	}
+
+	itemTypes, ok := diffItemTypes[itemType]
+	if !ok {
+		return 0, errors.New("unknown command")
+	}
+
+	var schemaNames []string
+	var err error
+	switch p.command.Clause {
+	case IN:
+		// schemas only in the database are compared, too
+		schemaNames = p.command.ExtArgs
+	case EXCEPT_IN:
+		schemaNames, err = p.getSchemaNames(nil, p.command.ExtArgs)
+	default:
+		schemaNames, err = p.getSchemaNames(nil, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sourceItems := []*dbdr.SchemaItemInfo{}
+	for _, schemaName := range schemaNames {
+		for _, t := range itemTypes {
+			items, err := p.getSourceSchemaItems(schemaName, t)
+			if err != nil {
+				return 0, err
+			}
+			sourceItems = append(sourceItems, items...)
+		}
+	}
+
+	p.makeListInstruction(DIFF, map[string]interface{}{
+		SCHEMA_NAMES: schemaNames,
+		ITEM_TYPES:   itemTypes,
+		SOURCE_ITEMS: sourceItems,
+	})
+
+	return 1, nil
+}
+
+func (p *preprocessor) preprocessDiffSchemas() (int, error) {
+	except := []string{}
+	if p.command.Clause == "except" {
+		except = p.command.ExtArgs
+	}
+
+	schemaNames, err := p.getSchemaNames(nil, except)
+	if err != nil {
+		return 0, err
+	}
+
+	sourceItems := []*dbdr.SchemaItemInfo{}
+	for _, schemaName := range schemaNames {
+		sourceItems = append(sourceItems, &dbdr.SchemaItemInfo{ItemType: schemaItemTypeSchema, SchemaName: schemaName})
+	}
+
+	p.makeListInstruction(DIFF, map[string]interface{}{
+		EXCLUDED_SCHEMA_NAMES: except,
+		ITEM_TYPES:            []string{schemaItemTypeSchema},
+		SOURCE_ITEMS:          sourceItems,
+	})
+
+	return 1, nil
+}
+
+// getSourceSchemaItems returns the items of a type the source repo declares in a schema. Types
+// are declared by files, everything else by directories.
+func (p *preprocessor) getSourceSchemaItems(schemaName, itemType string) ([]*dbdr.SchemaItemInfo, error) {
+	if err := p.ensureSourceDriverOpen(); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	if itemType == dbdr.SchemaItemTypeType {
+		fileReaders, err := p.sourceDriver.ReadFiles(fmt.Sprintf("schemas/%s/types", schemaName), `.*\.create\.sql`)
+		if err != nil {
+			return nil, err
+		}
+		for _, fr := range fileReaders {
+			names = append(names, strings.TrimSuffix(path.Base(fr.Name), ".create.sql"))
+		}
+	} else {
+		dirReaders, err := p.sourceDriver.ReadDirectories(fmt.Sprintf(diffSourceDirs[itemType], schemaName), ".*")
+		if err != nil {
+			return nil, err
+		}
+		for _, dr := range dirReaders {
+			names = append(names, path.Base(dr.DirectoryPath))
+		}
+	}
+
+	items := []*dbdr.SchemaItemInfo{}
+	for _, name := range names {
+		items = append(items, &dbdr.SchemaItemInfo{ItemType: itemType, SchemaName: schemaName, ItemName: name})
+	}
+	return items, nil
+}
+
+func (p *processor) renderDiff(instr *instruction) error {
+	itemTypes := instr.params[ITEM_TYPES].([]string)
+	sourceItems := instr.params[SOURCE_ITEMS].([]*dbdr.SchemaItemInfo)
+
+	var dbItems []*dbdr.SchemaItemInfo
+	var err error
+	if len(itemTypes) == 1 && itemTypes[0] == schemaItemTypeSchema {
+		dbItems, err = p.getDatabaseSchemas(instr.params[EXCLUDED_SCHEMA_NAMES].([]string))
+	} else {
+		dbItems, err = p.getDatabaseSchemaItems(instr.params[SCHEMA_NAMES].([]string), itemTypes)
+	}
+	if err != nil {
+		return err
+	}
+
+	header := []string{"Schema Name", "Item Name", "Item Type", "Status"}
+	data := diffSchemaItems(sourceItems, dbItems)
+	if err := p.listOutput(header, data); err != nil {
+		return err
+	}
+
+	// a non-zero exit lets deploys be gated on the database matching the source repo
+	drift := 0
+	for _, row := range data {
+		if row[3] != DIFF_PRESENT {
+			drift++
+		}
+	}
+	if drift > 0 {
+		return fmt.Errorf("%d item(s) differ between the source repo and the database", drift)
+	}
+	return nil
+}
+
+// getDatabaseSchemas returns the database schemas except those excluded
+func (p *processor) getDatabaseSchemas(except []string) ([]*dbdr.SchemaItemInfo, error) {
+	schemaNames, err := p.ctx.dbDriver.Schemas()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*dbdr.SchemaItemInfo{}
+	for _, schemaName := range schemaNames {
+		if !sliceutil.Contains(except, schemaName) {
+			items = append(items, &dbdr.SchemaItemInfo{ItemType: schemaItemTypeSchema, SchemaName: schemaName})
+		}
+	}
+	return items, nil
+}
+
+// getDatabaseSchemaItems returns the items of the types in the database schemas
+func (p *processor) getDatabaseSchemaItems(schemaNames, itemTypes []string) ([]*dbdr.SchemaItemInfo, error) {
+	items := []*dbdr.SchemaItemInfo{}
+	for _, schemaName := range schemaNames {
+		for _, t := range itemTypes {
+			infos, err := diffDatabaseItems[t](p.ctx.dbDriver, schemaName)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				if t == dbdr.SchemaItemTypeTable && sliceutil.Contains(bookkeepingTables, info.ItemName) {
+					continue
+				}
+				items = append(items, info)
+			}
+		}
+	}
+	return items, nil
+}
+
+// diffSchemaItems returns a row for each item declared in the source repo or found in the database,
+// sorted by schema, type and name, with its status. Items are compared by schema, type and name, so
+// overloaded functions are listed once.
+func diffSchemaItems(sourceItems, dbItems []*dbdr.SchemaItemInfo) [][]string {
+	key := func(i *dbdr.SchemaItemInfo) [3]string {
+		return [3]string{i.SchemaName, i.ItemType, i.ItemName}
+	}
+
+	inSource := map[[3]string]bool{}
+	for _, i := range sourceItems {
+		inSource[key(i)] = true
+	}
+	inDatabase := map[[3]string]bool{}
+	for _, i := range dbItems {
+		inDatabase[key(i)] = true
+	}
+
+	keys := [][3]string{}
+	for k := range inSource {
+		keys = append(keys, k)
+	}
+	for k := range inDatabase {
+		if !inSource[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for n := range keys[i] {
+			if keys[i][n] != keys[j][n] {
+				return keys[i][n] < keys[j][n]
+			}
+		}
+		return false
+	})
+
+	data := [][]string{}
+	for _, k := range keys {
+		status := DIFF_PRESENT
+		switch {
+		case !inDatabase[k]:
+			status = DIFF_MISSING
+		case !inSource[k]:
+			status = DIFF_EXTRA
+		}
+		data = append(data, []string{k[0], k[2], k[1], status})
+	}
+	return data
+}
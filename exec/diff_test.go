@@ -0,0 +1,57 @@
+package exec
+
+import (
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func schemaItem(itemType, schemaName, itemName string) *dbdr.SchemaItemInfo {
+	return &dbdr.SchemaItemInfo{ItemType: itemType, SchemaName: schemaName, ItemName: itemName}
+}
+
+var _ = ginkgo.Describe("diff.go", func() {
+
+	ginkgo.It("preprocesses diffs", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir}
+		cmds, _, _, err := parser.Parse("diff schema-items in foo_schema")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		instrs := ctx.instructions[1:]
+		Expect(len(instrs)).To(Equal(1))
+		Expect(instrs[0].params[ITEM_TYPE]).To(Equal(DIFF))
+		Expect(instrs[0].params[SCHEMA_NAMES]).To(Equal([]string{"foo_schema"}))
+
+		sourceItems := instrs[0].params[SOURCE_ITEMS].([]*dbdr.SchemaItemInfo)
+		Expect(sourceItems).To(ContainElement(schemaItem(dbdr.SchemaItemTypeTable, "foo_schema", "bar_table")))
+		Expect(sourceItems).To(ContainElement(schemaItem(dbdr.SchemaItemTypeView, "foo_schema", "foo_view")))
+		Expect(sourceItems).To(ContainElement(schemaItem(dbdr.SchemaItemTypeFunction, "foo_schema", "baz_function")))
+		Expect(sourceItems).To(ContainElement(schemaItem(dbdr.SchemaItemTypeProcedure, "foo_schema", "foo_procedure")))
+		Expect(sourceItems).To(ContainElement(schemaItem(dbdr.SchemaItemTypeType, "foo_schema", "foo_type")))
+		Expect(len(sourceItems)).To(Equal(13))
+	})
+
+	ginkgo.It("compares source and database items", func() {
+		sourceItems := []*dbdr.SchemaItemInfo{
+			schemaItem(dbdr.SchemaItemTypeTable, "foo", "b"),
+			schemaItem(dbdr.SchemaItemTypeTable, "foo", "a"),
+			schemaItem(dbdr.SchemaItemTypeView, "foo", "a"),
+		}
+		dbItems := []*dbdr.SchemaItemInfo{
+			schemaItem(dbdr.SchemaItemTypeTable, "foo", "a"),
+			schemaItem(dbdr.SchemaItemTypeTable, "bar", "c"),
+			schemaItem(dbdr.SchemaItemTypeFunction, "foo", "f"),
+			schemaItem(dbdr.SchemaItemTypeFunction, "foo", "f"),
+		}
+		Expect(diffSchemaItems(sourceItems, dbItems)).To(Equal([][]string{
+			{"bar", "c", dbdr.SchemaItemTypeTable, DIFF_EXTRA},
+			{"foo", "f", dbdr.SchemaItemTypeFunction, DIFF_EXTRA},
+			{"foo", "a", dbdr.SchemaItemTypeTable, DIFF_PRESENT},
+			{"foo", "b", dbdr.SchemaItemTypeTable, DIFF_MISSING},
+			{"foo", "a", dbdr.SchemaItemTypeView, DIFF_MISSING},
+		}))
+	})
+})
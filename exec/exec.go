@@ -1,22 +1,41 @@
 package exec
 
 import (
+	"github.com/nrfta/ddsl/drivers/database/mysql"
 	"github.com/nrfta/ddsl/drivers/database/postgres"
+	"github.com/nrfta/ddsl/drivers/database/sqlite"
+	"github.com/nrfta/ddsl/drivers/source/archive"
 	"github.com/nrfta/ddsl/drivers/source/file"
+	"github.com/nrfta/ddsl/drivers/source/git"
+	"github.com/nrfta/ddsl/drivers/source/overlay"
 	"github.com/nrfta/ddsl/parser"
 )
 
 func init() {
+	mysql.Register()
 	postgres.Register()
+	sqlite.Register()
+	archive.Register()
 	file.Register()
+	git.Register()
+	overlay.Register()
 }
 
 func ExecuteBatch(ctx *Context, cmds []*parser.Command) error {
+	// the context is reused by the repl, so start each batch clean
+	ctx.clearInstructions()
+	ctx.clearMigrationLedger()
+	ctx.resetNesting()
+	defer ctx.closeSourceDrivers()
+
 	_, err := preprocessBatch(ctx, cmds)
 	if err != nil {
 		return err
 	}
 
-	p := &processor{ctx}
+	p := &processor{ctx: ctx}
+	if ctx.Plan {
+		return p.renderPlan()
+	}
 	return p.process()
 }
@@ -1,6 +1,9 @@
 package exec
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/onsi/ginkgo"
@@ -11,3 +14,13 @@ func TestExec(t *testing.T) {
 	RegisterFailHandler(ginkgo.Fail)
 	ginkgo.RunSpecs(t, "Exec Suite")
 }
+
+// writeFiles writes the files, given by their paths relative to dir, for tests that need a source
+// repo of their own
+func writeFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(filePath), 0755)).To(BeNil())
+		Expect(ioutil.WriteFile(filePath, []byte(content), 0644)).To(BeNil())
+	}
+}
@@ -0,0 +1,164 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+)
+
+// extractFile is a file of the definitions of a schema item
+type extractFile struct {
+	definitionType string
+
+	// name is the name of the file, formatted with create or drop, or grant or revoke
+	name string
+}
+
+// extractDirs are the directories of a schema holding a directory per item of a type
+var extractDirs = map[string]string{
+	dbdr.SchemaItemTypeTable:     "tables",
+	dbdr.SchemaItemTypeView:      "views",
+	dbdr.SchemaItemTypeFunction:  "functions",
+	dbdr.SchemaItemTypeProcedure: "procedures",
+}
+
+// extractFiles are the files in the directory of an item of a type
+var extractFiles = map[string][]extractFile{
+	dbdr.SchemaItemTypeTable: {
+		{dbdr.SchemaItemTypeTable, "table.%s.sql"},
+		{dbdr.DefinitionTypeIndexes, "indexes.%s.sql"},
+		{dbdr.DefinitionTypeConstraints, "constraints.%s.sql"},
+		{dbdr.DefinitionTypeForeignKeys, "foreign-keys.%s.sql"},
+		{dbdr.DefinitionTypeTriggers, "triggers.%s.sql"},
+		{dbdr.DefinitionTypeTablePrivileges, "privileges.%s.sql"},
+	},
+	dbdr.SchemaItemTypeView: {
+		{dbdr.SchemaItemTypeView, "view.%s.sql"},
+		{dbdr.DefinitionTypeIndexes, "indexes.%s.sql"},
+		{dbdr.DefinitionTypeTablePrivileges, "privileges.%s.sql"},
+	},
+	dbdr.SchemaItemTypeFunction: {
+		{dbdr.SchemaItemTypeFunction, "function.%s.sql"},
+		{dbdr.DefinitionTypeRoutinePrivileges, "privileges.%s.sql"},
+	},
+	dbdr.SchemaItemTypeProcedure: {
+		{dbdr.SchemaItemTypeProcedure, "procedure.%s.sql"},
+		{dbdr.DefinitionTypeRoutinePrivileges, "privileges.%s.sql"},
+	},
+}
+
+// preprocessExtract adds an instruction to write the definitions of database schemas to a
+// file:// source repo. The schemas are read from the database when processing.
+func (p *preprocessor) preprocessExtract() (int, error) {
+	dir, err := localSourcePath(p.ctx.SourceRepo)
+	if err != nil {
+		return 0, err
+	}
+
+	params := map[string]interface{}{FILE_PATH: dir}
+	switch p.command.CommandDef.Name {
+	case SCHEMAS:
+		except := []string{}
+		if p.command.Clause == "except" {
+			except = p.command.ExtArgs
+		}
+		params[EXCLUDED_SCHEMA_NAMES] = except
+	case SCHEMA:
+		params[SCHEMA_NAMES] = p.command.ExtArgs
+	default:
+		return 0, errors.New("unknown command")
+	}
+
+	p.ctx.addInstructionWithParams(INSTR_EXTRACT, params)
+
+	return 1, nil
+}
+
+// extract writes the definitions of the schemas and their items to the source repo. Existing
+// files are never overwritten.
+func (p *processor) extract(instr *instruction) error {
+	dir := instr.params[FILE_PATH].(string)
+
+	schemaNames, ok := instr.params[SCHEMA_NAMES].([]string)
+	if !ok {
+		schemas, err := p.getDatabaseSchemas(instr.params[EXCLUDED_SCHEMA_NAMES].([]string))
+		if err != nil {
+			return err
+		}
+		for _, schema := range schemas {
+			schemaNames = append(schemaNames, schema.SchemaName)
+		}
+	}
+
+	for _, schemaName := range schemaNames {
+		schemaDir := path.Join(dir, "schemas", schemaName)
+		err := p.extractDefinitions(path.Join(schemaDir, "schema.%s.sql"), dbdr.DefinitionTypeSchema, schemaName, schemaName)
+		if err != nil {
+			return err
+		}
+
+		items, err := p.getDatabaseSchemaItems([]string{schemaName}, diffItemTypes[SCHEMA_ITEMS])
+		if err != nil {
+			return err
+		}
+
+		// overloaded functions are listed once per overload and extracted together
+		extracted := map[[2]string]bool{}
+		for _, item := range items {
+			key := [2]string{item.ItemType, item.ItemName}
+			if extracted[key] {
+				continue
+			}
+			extracted[key] = true
+
+			if item.ItemType == dbdr.SchemaItemTypeType {
+				pattern := path.Join(schemaDir, "types", item.ItemName+".%s.sql")
+				if err = p.extractDefinitions(pattern, item.ItemType, schemaName, item.ItemName); err != nil {
+					return err
+				}
+				continue
+			}
+
+			itemDir := path.Join(schemaDir, extractDirs[item.ItemType], item.ItemName)
+			for _, f := range extractFiles[item.ItemType] {
+				if err = p.extractDefinitions(path.Join(itemDir, f.name), f.definitionType, schemaName, item.ItemName); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// extractDefinitions writes the create and drop statements of the definitions to the files of
+// the pattern, dropping in reverse order. Privileges are written to grant and revoke files.
+// Nothing is written when the object has no definitions.
+func (p *processor) extractDefinitions(pattern, definitionType, schemaName, name string) error {
+	definitions, err := p.ctx.dbDriver.Definitions(definitionType, schemaName, name)
+	if err != nil {
+		return err
+	}
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	creates, drops := []string{}, []string{}
+	for i, d := range definitions {
+		creates = append(creates, d.Create)
+		drops = append(drops, definitions[len(definitions)-1-i].Drop)
+	}
+
+	create, drop := CREATE, DROP
+	if definitionType == dbdr.DefinitionTypeTablePrivileges || definitionType == dbdr.DefinitionTypeRoutinePrivileges {
+		create, drop = GRANT, REVOKE
+	}
+
+	if err = p.createFile(fmt.Sprintf(pattern, create), strings.Join(creates, "\n\n")+"\n"); err != nil {
+		return err
+	}
+	return p.createFile(fmt.Sprintf(pattern, drop), strings.Join(drops, "\n\n")+"\n")
+}
@@ -0,0 +1,41 @@
+package exec
+
+import (
+	"path/filepath"
+
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("extract.go", func() {
+
+	ginkgo.It("preprocesses extracts", func() {
+		dir, err := filepath.Abs(sourceDir)
+		Expect(err).To(BeNil())
+
+		for _, tc := range []struct {
+			command string
+			params  map[string]interface{}
+		}{
+			{"extract schemas", map[string]interface{}{FILE_PATH: dir, EXCLUDED_SCHEMA_NAMES: []string{}}},
+			{"extract schemas except public", map[string]interface{}{FILE_PATH: dir, EXCLUDED_SCHEMA_NAMES: []string{"public"}}},
+			{"extract schema foo_schema,bar_schema", map[string]interface{}{FILE_PATH: dir, SCHEMA_NAMES: []string{"foo_schema", "bar_schema"}}},
+		} {
+			ctx := &Context{SourceRepo: "file://" + sourceDir}
+			cmds, _, _, err := parser.Parse(tc.command)
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			Expect(err).To(BeNil(), tc.command)
+			Expect(ctx.instructions[1:]).To(Equal([]*instruction{{INSTR_EXTRACT, tc.params}}), tc.command)
+		}
+	})
+
+	ginkgo.It("requires a file source", func() {
+		ctx := &Context{SourceRepo: "git://" + sourceDir}
+		cmds, _, _, err := parser.Parse("extract schemas")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(HaveOccurred())
+	})
+})
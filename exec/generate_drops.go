@@ -0,0 +1,479 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nrfta/ddsl/log"
+)
+
+const (
+	// generate drops check statuses
+	DROPS_MISSING_FILE = "missing drop file"
+	DROPS_NOT_DROPPED  = "not dropped"
+
+	// sqlObjectTypeConstraint is the object type of constraints added by ALTER TABLE
+	sqlObjectTypeConstraint = "CONSTRAINT"
+)
+
+// generateDropsKeys are the pathPatterns keys of the create files drop files are generated for
+var generateDropsKeys = []string{
+	SCHEMA,
+	TYPES,
+	TABLES,
+	VIEWS,
+	FUNCTIONS,
+	PROCEDURES,
+	CONSTRAINTS,
+	FOREIGN_KEYS,
+	INDEXES,
+	TRIGGERS,
+}
+
+// sqlObject is an object defined by a create statement
+type sqlObject struct {
+	objectType string
+	name       string
+
+	// args are the arguments of a function or procedure
+	args string
+
+	// table is the table of an index, trigger or constraint
+	table string
+}
+
+const sqlName = "(?:\"[^\"]*\"|`[^`]*`|[^\\s\"`(),;.]+)"
+const sqlQualifiedName = sqlName + `(?:\s*\.\s*` + sqlName + `)*`
+
+var (
+	createStatementRegexp = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:(?:GLOBAL|LOCAL|TEMP|TEMPORARY|UNLOGGED|UNIQUE|DEFINER\s*=\s*\S+)\s+)*` +
+		`(MATERIALIZED\s+VIEW|TABLE|VIEW|FUNCTION|PROCEDURE|TYPE|DOMAIN|INDEX|TRIGGER|SCHEMA|SEQUENCE|EXTENSION)\s+` +
+		`(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + sqlQualifiedName + `)`)
+	onTableRegexp        = regexp.MustCompile(`(?is)\sON\s+(?:ONLY\s+)?(` + sqlQualifiedName + `)`)
+	alterTableRegexp     = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + sqlQualifiedName + `)`)
+	addConstraintRegexp  = regexp.MustCompile(`(?is)\bADD\s+CONSTRAINT\s+(` + sqlName + `)`)
+	dropConstraintRegexp = regexp.MustCompile(`(?is)\bDROP\s+(?:CONSTRAINT|FOREIGN\s+KEY|CHECK)\s+(?:IF\s+EXISTS\s+)?(` + sqlName + `)`)
+	dropStatementRegexp  = regexp.MustCompile(`(?is)^DROP\s+(MATERIALIZED\s+VIEW|TABLE|VIEW|FUNCTION|PROCEDURE|TYPE|DOMAIN|INDEX|TRIGGER|SCHEMA|SEQUENCE|EXTENSION)\s+` +
+		`(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?`)
+	dropOptionsRegexp     = regexp.MustCompile(`(?is)\s+(?:CASCADE|RESTRICT|ON\s+.*)$`)
+	argumentDefaultRegexp = regexp.MustCompile(`(?is)\s*(?:\bDEFAULT\b|=).*$`)
+	whitespaceRegexp      = regexp.MustCompile(`\s+`)
+	dollarQuoteRegexp     = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+)
+
+// preprocessGenerateDrops adds instructions to write the missing drop files of the create files
+// in the source repo or, with check, a list instruction reporting the drop files that are
+// missing or do not drop every object their create file defines.
+func (p *preprocessor) preprocessGenerateDrops() (int, error) {
+	if p.command.CommandDef.Name != DROPS {
+		return 0, errors.New("unknown command")
+	}
+
+	check := strings.HasPrefix(p.command.Clause, CHECK)
+	clause := strings.TrimSpace(strings.TrimPrefix(p.command.Clause, CHECK))
+
+	var schemaNames []string
+	var err error
+	switch clause {
+	case IN:
+		schemaNames, err = p.getSchemaNames(p.command.ExtArgs, nil)
+	case EXCEPT_IN:
+		schemaNames, err = p.getSchemaNames(nil, p.command.ExtArgs)
+	default:
+		schemaNames, err = p.getSchemaNames(nil, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	dir := ""
+	if !check {
+		if dir, err = localSourcePath(p.ctx.SourceRepo); err != nil {
+			return 0, err
+		}
+	}
+
+	data := [][]string{}
+	count := 0
+	for _, schemaName := range schemaNames {
+		for _, key := range generateDropsKeys {
+			pathPattern := pathPatterns[key]
+			params := []interface{}{schemaName}
+			for i := 2; i < strings.Count(pathPattern, "%s"); i++ {
+				params = append(params, "?")
+			}
+			params = append(params, CREATE)
+
+			relativePath, filePattern := path.Split(fmt.Sprintf(pathPattern, params...))
+			dirs, err := p.resolveDirectoryWildcards(relativePath)
+			if err != nil {
+				return 0, err
+			}
+
+			for _, d := range dirs {
+				rows, c, err := p.generateDrops(d, filePattern, dir, check)
+				if err != nil {
+					return 0, err
+				}
+				data = append(data, rows...)
+				count += c
+			}
+		}
+	}
+
+	if check {
+		p.makeListInstruction(DROPS, map[string]interface{}{DROPS: data})
+		return 1, nil
+	}
+
+	return count, nil
+}
+
+// generateDrops compares the create files of a directory with their drop files. It returns the
+// check rows of the directory or, when not checking, adds an instruction to write each missing
+// drop file. Existing drop files are never overwritten.
+func (p *preprocessor) generateDrops(relativeDir, fileNamePattern, dir string, check bool) ([][]string, int, error) {
+	fileReaders, err := p.readFiles(relativeDir, fileNamePattern)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := [][]string{}
+	count := 0
+	for _, fr := range fileReaders {
+		createPath := path.Join(relativeDir, path.Base(fr.Name))
+		dropPath := strings.TrimSuffix(createPath, ".create.sql") + ".drop.sql"
+
+		content, err := readAll(fr)
+		if err != nil {
+			return nil, 0, err
+		}
+		objects := parseCreateStatements(string(content))
+
+		dropReaders, err := p.readFiles(relativeDir, "^"+regexp.QuoteMeta(path.Base(dropPath))+"$")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(dropReaders) == 0 {
+			if check {
+				if len(objects) == 0 {
+					data = append(data, []string{dropPath, "", "", DROPS_MISSING_FILE})
+				}
+				for _, o := range objects {
+					data = append(data, []string{dropPath, o.objectType, o.name, DROPS_MISSING_FILE})
+				}
+				continue
+			}
+
+			if len(objects) == 0 {
+				log.Debug("no objects are created by %s", createPath)
+				continue
+			}
+			p.ctx.addInstructionWithParams(INSTR_WRITE_FILE, map[string]interface{}{
+				FILE_PATH: path.Join(dir, dropPath),
+				CONTENT:   generateDropStatements(objects),
+			})
+			count++
+			continue
+		}
+
+		if !check {
+			log.Debug("%s already exists", dropPath)
+			continue
+		}
+
+		dropContent, err := readAll(dropReaders[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, o := range undroppedObjects(objects, parseDropStatements(string(dropContent))) {
+			data = append(data, []string{dropPath, o.objectType, o.name, DROPS_NOT_DROPPED})
+		}
+	}
+
+	return data, count, nil
+}
+
+func (p *processor) renderDropsCheck(instr *instruction) error {
+	header := []string{"File", "Object Type", "Object Name", "Status"}
+	data := instr.params[DROPS].([][]string)
+	if err := p.listOutput(header, data); err != nil {
+		return err
+	}
+
+	// a non-zero exit lets CI fail when a drop file falls behind its create file
+	if len(data) > 0 {
+		return fmt.Errorf("%d object(s) are not dropped by the drop files", len(data))
+	}
+	return nil
+}
+
+// generateDropStatements returns the statements dropping the objects in reverse order, in the
+// SQL of Postgres
+func generateDropStatements(objects []*sqlObject) string {
+	drops := []string{}
+	for i := len(objects) - 1; i >= 0; i-- {
+		o := objects[i]
+		switch o.objectType {
+		case sqlObjectTypeConstraint:
+			drops = append(drops, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", o.table, o.name))
+		case "TRIGGER":
+			drops = append(drops, fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", o.name, o.table))
+		case "FUNCTION", "PROCEDURE":
+			// before Postgres 10 functions are only dropped by their arguments, which also tell
+			// overloads apart
+			drops = append(drops, fmt.Sprintf("DROP %s IF EXISTS %s(%s);", o.objectType, o.name, o.args))
+		default:
+			drops = append(drops, fmt.Sprintf("DROP %s IF EXISTS %s;", o.objectType, o.name))
+		}
+	}
+
+	return strings.Join(drops, "\n") + "\n"
+}
+
+// undroppedObjects returns the objects not dropped by the drop statements. Overloads are not
+// told apart, and indexes, triggers and constraints are dropped with their table.
+func undroppedObjects(objects, drops []*sqlObject) []*sqlObject {
+	dropped := map[string]bool{}
+	for _, d := range drops {
+		dropped[d.objectType+" "+unqualifiedName(d.name)] = true
+	}
+
+	result := []*sqlObject{}
+	for _, o := range objects {
+		if dropped[o.objectType+" "+unqualifiedName(o.name)] {
+			continue
+		}
+		if len(o.table) > 0 && dropped["TABLE "+unqualifiedName(o.table)] {
+			continue
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+// parseCreateStatements returns the objects defined by the CREATE and ALTER TABLE ... ADD
+// CONSTRAINT statements of a SQL script
+func parseCreateStatements(sql string) []*sqlObject {
+	objects := []*sqlObject{}
+	for _, stmt := range splitStatements(sql) {
+		if m := alterTableRegexp.FindStringSubmatch(stmt); m != nil {
+			table := normalizeSQLName(m[1])
+			for _, c := range addConstraintRegexp.FindAllStringSubmatch(stmt, -1) {
+				objects = append(objects, &sqlObject{objectType: sqlObjectTypeConstraint, name: c[1], table: table})
+			}
+			continue
+		}
+
+		m := createStatementRegexp.FindStringSubmatchIndex(stmt)
+		if m == nil {
+			continue
+		}
+		o := &sqlObject{
+			objectType: strings.ToUpper(whitespaceRegexp.ReplaceAllString(stmt[m[2]:m[3]], " ")),
+			name:       normalizeSQLName(stmt[m[4]:m[5]]),
+		}
+		rest := stmt[m[5]:]
+
+		switch o.objectType {
+		case "FUNCTION", "PROCEDURE":
+			o.args = parseArgumentTypes(rest)
+		case "INDEX":
+			// the name of an index is optional, and an unnamed index cannot be dropped by name
+			if strings.EqualFold(o.name, "ON") {
+				continue
+			}
+			t := onTableRegexp.FindStringSubmatch(rest)
+			if t == nil {
+				continue
+			}
+			o.table = normalizeSQLName(t[1])
+			// an index is created in the schema of its table
+			if i := strings.LastIndex(o.table, "."); i > 0 && !strings.Contains(o.name, ".") {
+				o.name = o.table[:i] + "." + o.name
+			}
+		case "TRIGGER":
+			t := onTableRegexp.FindStringSubmatch(rest)
+			if t == nil {
+				continue
+			}
+			o.table = normalizeSQLName(t[1])
+		}
+
+		objects = append(objects, o)
+	}
+	return objects
+}
+
+// parseDropStatements returns the objects dropped by the DROP and ALTER TABLE ... DROP
+// CONSTRAINT statements of a SQL script
+func parseDropStatements(sql string) []*sqlObject {
+	objects := []*sqlObject{}
+	for _, stmt := range splitStatements(sql) {
+		if m := alterTableRegexp.FindStringSubmatch(stmt); m != nil {
+			table := normalizeSQLName(m[1])
+			for _, c := range dropConstraintRegexp.FindAllStringSubmatch(stmt, -1) {
+				objects = append(objects, &sqlObject{objectType: sqlObjectTypeConstraint, name: c[1], table: table})
+			}
+			continue
+		}
+
+		m := dropStatementRegexp.FindStringSubmatchIndex(stmt)
+		if m == nil {
+			continue
+		}
+		objectType := strings.ToUpper(whitespaceRegexp.ReplaceAllString(stmt[m[2]:m[3]], " "))
+
+		// a drop statement can drop a comma-delimited list of objects
+		names := dropOptionsRegexp.ReplaceAllString(stmt[m[1]:], "")
+		for _, name := range splitTopLevel(names, ',') {
+			if i := strings.Index(name, "("); i >= 0 {
+				name = name[:i]
+			}
+			objects = append(objects, &sqlObject{objectType: objectType, name: normalizeSQLName(name)})
+		}
+	}
+	return objects
+}
+
+// parseArgumentTypes returns the arguments in the parentheses at the start of the text without
+// their defaults, so they can identify an overload in a drop statement
+func parseArgumentTypes(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "(") {
+		return ""
+	}
+
+	depth := 0
+	end := -1
+	for i, r := range text {
+		if r == '(' {
+			depth++
+		} else if r == ')' {
+			depth--
+			if depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return ""
+	}
+
+	args := []string{}
+	for _, arg := range splitTopLevel(text[1:end], ',') {
+		arg = argumentDefaultRegexp.ReplaceAllString(arg, "")
+		if len(arg) > 0 {
+			args = append(args, whitespaceRegexp.ReplaceAllString(arg, " "))
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// normalizeSQLName removes the whitespace around the dots of a qualified name
+func normalizeSQLName(name string) string {
+	return strings.Join(splitTopLevel(name, '.'), ".")
+}
+
+// unqualifiedName returns the last part of a qualified name, unquoted, and lower case unless it
+// is quoted
+func unqualifiedName(name string) string {
+	parts := splitTopLevel(name, '.')
+	last := parts[len(parts)-1]
+	if len(last) >= 2 && (last[0] == '"' || last[0] == '`') && last[len(last)-1] == last[0] {
+		return last[1 : len(last)-1]
+	}
+	return strings.ToLower(last)
+}
+
+// splitTopLevel splits text at the separators outside of quotes and parentheses and trims the parts
+func splitTopLevel(text string, separator rune) []string {
+	parts := []string{}
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == separator && depth == 0:
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(text[start:]))
+}
+
+// splitStatements splits a SQL script into its statements without comments. Semicolons in
+// quotes, dollar quotes and comments do not end a statement.
+func splitStatements(sql string) []string {
+	statements := []string{}
+	var b strings.Builder
+	add := func() {
+		if stmt := strings.TrimSpace(b.String()); len(stmt) > 0 {
+			statements = append(statements, stmt)
+		}
+		b.Reset()
+	}
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			b.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			b.WriteByte(' ')
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				b.WriteString(sql[i:])
+				i = len(sql)
+				break
+			}
+			b.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case c == '$' && dollarQuoteRegexp.MatchString(sql[i:]):
+			tag := dollarQuoteRegexp.FindString(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				b.WriteString(sql[i:])
+				i = len(sql)
+				break
+			}
+			b.WriteString(sql[i : i+2*len(tag)+end])
+			i += 2*len(tag) + end - 1
+		case c == ';':
+			add()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	add()
+
+	return statements
+}
@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const createStatements = `-- a semicolon in a comment; is ignored
+CREATE TABLE IF NOT EXISTS foo_schema.foo_table (
+  id integer PRIMARY KEY,
+  note text DEFAULT 'a;b'
+);
+
+CREATE UNIQUE INDEX foo_table_note_idx ON foo_schema.foo_table (note);
+
+ALTER TABLE foo_schema.foo_table ADD CONSTRAINT foo_table_note_check CHECK (note <> '');
+
+CREATE OR REPLACE FUNCTION foo_schema.foo_function(a integer, b text DEFAULT 'x') RETURNS integer AS $$
+BEGIN
+  RETURN a;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION foo_schema.foo_function(a text) RETURNS integer AS $body$ SELECT 1; $body$ LANGUAGE sql;
+
+CREATE TRIGGER foo_trigger AFTER INSERT ON foo_schema.foo_table FOR EACH ROW EXECUTE FUNCTION foo_schema.foo_function();
+`
+
+var _ = ginkgo.Describe("generate_drops.go", func() {
+
+	ginkgo.It("parses create statements", func() {
+		objects := parseCreateStatements(createStatements)
+		Expect(objects).To(Equal([]*sqlObject{
+			{objectType: "TABLE", name: "foo_schema.foo_table"},
+			{objectType: "INDEX", name: "foo_schema.foo_table_note_idx", table: "foo_schema.foo_table"},
+			{objectType: "CONSTRAINT", name: "foo_table_note_check", table: "foo_schema.foo_table"},
+			{objectType: "FUNCTION", name: "foo_schema.foo_function", args: "a integer, b text"},
+			{objectType: "FUNCTION", name: "foo_schema.foo_function", args: "a text"},
+			{objectType: "TRIGGER", name: "foo_trigger", table: "foo_schema.foo_table"},
+		}))
+	})
+
+	ginkgo.It("generates drop statements in reverse order", func() {
+		Expect(generateDropStatements(parseCreateStatements(createStatements))).To(Equal(
+			"DROP TRIGGER IF EXISTS foo_trigger ON foo_schema.foo_table;\n" +
+				"DROP FUNCTION IF EXISTS foo_schema.foo_function(a text);\n" +
+				"DROP FUNCTION IF EXISTS foo_schema.foo_function(a integer, b text);\n" +
+				"ALTER TABLE foo_schema.foo_table DROP CONSTRAINT IF EXISTS foo_table_note_check;\n" +
+				"DROP INDEX IF EXISTS foo_schema.foo_table_note_idx;\n" +
+				"DROP TABLE IF EXISTS foo_schema.foo_table;\n"))
+
+		// functions are dropped by their arguments even when they are not overloaded
+		Expect(generateDropStatements(parseCreateStatements(
+			"CREATE PROCEDURE foo_schema.foo_procedure(a integer) AS $$ SELECT 1; $$ LANGUAGE sql;\n" +
+				"CREATE FUNCTION foo_schema.bar_function() RETURNS integer AS $$ SELECT 1; $$ LANGUAGE sql;"))).To(Equal(
+			"DROP FUNCTION IF EXISTS foo_schema.bar_function();\n" +
+				"DROP PROCEDURE IF EXISTS foo_schema.foo_procedure(a integer);\n"))
+	})
+
+	ginkgo.It("finds objects that are not dropped", func() {
+		objects := parseCreateStatements(createStatements)
+
+		// the table drops its index, constraint and trigger
+		drops := parseDropStatements(`DROP FUNCTION foo_schema.foo_function(text), foo_schema."foo_function" CASCADE;
+DROP TABLE IF EXISTS FOO_SCHEMA.FOO_TABLE;`)
+		Expect(undroppedObjects(objects, drops)).To(BeEmpty())
+
+		drops = parseDropStatements(`DROP INDEX foo_schema.foo_table_note_idx;
+ALTER TABLE foo_schema.foo_table DROP CONSTRAINT IF EXISTS foo_table_note_check;`)
+		Expect(undroppedObjects(objects, drops)).To(Equal([]*sqlObject{objects[0], objects[3], objects[4], objects[5]}))
+
+		drops = parseDropStatements(`DROP TABLE foo_schema."FOO_TABLE";`)
+		Expect(len(undroppedObjects(objects, drops))).To(Equal(6))
+	})
+
+	ginkgo.It("checks drop files", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir}
+		cmds, _, _, err := parser.Parse("generate drops check in foo_schema")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		instrs := ctx.instructions[1:]
+		Expect(len(instrs)).To(Equal(1))
+		Expect(instrs[0].params[ITEM_TYPE]).To(Equal(DROPS))
+		Expect(instrs[0].params[DROPS]).To(BeEmpty())
+	})
+
+	ginkgo.It("generates missing drop files", func() {
+		dir, err := ioutil.TempDir("", "ddsl")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		writeFiles(dir, map[string]string{
+			"schemas/foo_schema/schema.create.sql":                        "CREATE SCHEMA foo_schema;\n",
+			"schemas/foo_schema/schema.drop.sql":                          "DROP SCHEMA foo_schema;\n",
+			"schemas/foo_schema/tables/foo_table/table.create.sql":        createStatements,
+			"schemas/foo_schema/tables/foo_table/foreign-keys.create.sql": "-- no foreign keys\n",
+		})
+
+		ctx := &Context{SourceRepo: "file://" + dir}
+		cmds, _, _, err := parser.Parse("generate drops")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		Expect(ctx.instructions[1:]).To(Equal([]*instruction{{INSTR_WRITE_FILE, map[string]interface{}{
+			FILE_PATH: filepath.Join(dir, "schemas/foo_schema/tables/foo_table/table.drop.sql"),
+			CONTENT:   generateDropStatements(parseCreateStatements(createStatements)),
+		}}}))
+
+		ctx = &Context{SourceRepo: "file://" + dir}
+		cmds, _, _, err = parser.Parse("generate drops check")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		data := ctx.instructions[1].params[DROPS].([][]string)
+		Expect(data).To(ContainElement([]string{"schemas/foo_schema/tables/foo_table/foreign-keys.drop.sql", "", "", DROPS_MISSING_FILE}))
+		Expect(data).To(ContainElement([]string{"schemas/foo_schema/tables/foo_table/table.drop.sql", "TABLE", "foo_schema.foo_table", DROPS_MISSING_FILE}))
+		Expect(len(data)).To(Equal(7))
+	})
+})
@@ -32,6 +32,12 @@ func (p *preprocessor) preprocessList() (int, error) {
 	case FOREIGN_KEYS:
 		p.makeListInstruction(FOREIGN_KEYS, map[string]interface{}{})
 		return 1, nil
+	case ROLES:
+		p.makeListInstruction(ROLES, map[string]interface{}{})
+		return 1, nil
+	case EXTENSIONS:
+		p.makeListInstruction(EXTENSIONS, map[string]interface{}{})
+		return 1, nil
 	case SCHEMA_ITEMS:
 		return p.preprocessListSchemaItems(SCHEMA_ITEMS)
 	case TABLES:
@@ -44,6 +50,14 @@ func (p *preprocessor) preprocessList() (int, error) {
 		return p.preprocessListSchemaItems(PROCEDURES)
 	case TYPES:
 		return p.preprocessListSchemaItems(TYPES)
+	case COLUMNS:
+		return p.preprocessListTableItems(COLUMNS)
+	case INDEXES:
+		return p.preprocessListTableItems(INDEXES)
+	case CONSTRAINTS:
+		return p.preprocessListTableItems(CONSTRAINTS)
+	case TRIGGERS:
+		return p.preprocessListTableItems(TRIGGERS)
 	}
 
 	return 0, errors.New("unknown command")
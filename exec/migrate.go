@@ -1,4 +1,614 @@
 package exec
 
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/drivers/source"
+	"github.com/nrfta/ddsl/log"
+	"github.com/nrfta/ddsl/parser"
+)
 
+const (
+	MIGRATIONS_REL_DIR     = "migrations"
+	MIGRATION_FILE_PATTERN = `^\d+_.+\.(up|down)\.ddsl$`
+
+	OUT_OF_ORDER_REJECT = "reject"
+	OUT_OF_ORDER_WARN   = "warn"
+	OUT_OF_ORDER_APPLY  = "apply"
+)
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.ddsl$`)
+
+type migration struct {
+	version int64
+	title   string
+	up      *source.FileReader
+	down    *source.FileReader
+}
+
+func (p *preprocessor) preprocessMigrate() (int, error) {
+	migrations, err := p.getMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	// new migrations only depend on the source repo
+	if p.command.CommandDef.Name == NEW {
+		return p.preprocessMigrateNew(migrations)
+	}
+
+	ledger, err := p.getLedger()
+	if err != nil {
+		return 0, err
+	}
+
+	switch p.command.CommandDef.Name {
+	case STATUS:
+		return p.preprocessMigrateStatus(migrations, ledger)
+	case VERIFY:
+		return p.preprocessMigrateVerify(migrations, ledger)
+	}
+
+	if err = ensureNotDirty(ledger); err != nil {
+		return 0, err
+	}
+
+	if err = p.ensureChecksumsMatch(migrations, ledger); err != nil {
+		return 0, err
+	}
+
+	switch p.command.CommandDef.Name {
+	case UP:
+		n, err := p.getNumberOfVersions()
+		if err != nil {
+			return 0, err
+		}
+		return p.migrateUp(migrations, ledger, n)
+	case DOWN:
+		n, err := p.getNumberOfVersions()
+		if err != nil {
+			return 0, err
+		}
+		return p.migrateDown(migrations, ledger, n)
+	case TOP:
+		return p.migrateUp(migrations, ledger, -1)
+	case BOTTOM:
+		return p.migrateDown(migrations, ledger, -1)
+	case TO:
+		return p.migrateTo(migrations, ledger)
+	case REDO:
+		n, err := p.getNumberOfVersions()
+		if err != nil {
+			return 0, err
+		}
+		return p.migrateRedo(migrations, ledger, n)
+	case BASELINE:
+		return p.migrateBaseline(migrations, ledger)
+	}
+
+	return 0, errors.New("unknown command")
+}
+
+func (p *preprocessor) migrateUp(migrations []*migration, ledger []*ledgerEntry, n int) (int, error) {
+	pending, err := p.orderedPendingMigrations(migrations, ledger)
+	if err != nil {
+		return 0, err
+	}
+	if n > -1 && n < len(pending) {
+		pending = pending[:n]
+	}
+
+	if len(pending) == 0 {
+		// nothing to do is not an error; count the command itself so the batch succeeds
+		log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "no migrations to apply; database is at version %d", currentVersion(ledger))
+		return 1, nil
+	}
+
+	return p.runMigrations(pending, UP)
+}
+
+func (p *preprocessor) migrateDown(migrations []*migration, ledger []*ledgerEntry, n int) (int, error) {
+	versions := appliedVersionsDesc(ledger)
+	if n > -1 && n < len(versions) {
+		versions = versions[:n]
+	}
+
+	if len(versions) == 0 {
+		log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "no migrations to revert; database is at version 0")
+		return 1, nil
+	}
+
+	reverting, err := findAppliedMigrations(migrations, versions)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.runMigrations(reverting, DOWN)
+}
+
+// migrateTo reverts the applied migrations above the target version and applies the
+// pending migrations up to and including it.
+func (p *preprocessor) migrateTo(migrations []*migration, ledger []*ledgerEntry) (int, error) {
+	target, err := p.getTargetVersion()
+	if err != nil {
+		return 0, err
+	}
+	if target != 0 && findMigration(migrations, target) == nil {
+		return 0, fmt.Errorf("migration version %d not found in %s", target, MIGRATIONS_REL_DIR)
+	}
+
+	versions := []int64{}
+	remaining := []*ledgerEntry{}
+	for _, e := range ledger {
+		if e.version > target {
+			versions = append(versions, e.version)
+		} else {
+			remaining = append(remaining, e)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	// out of order migrations are relative to the version after reverting
+	ordered, err := p.orderedPendingMigrations(migrations, remaining)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := []*migration{}
+	for _, m := range ordered {
+		if m.version <= target {
+			pending = append(pending, m)
+		}
+	}
+
+	if len(versions) == 0 && len(pending) == 0 {
+		log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "database is already at version %d", currentVersion(ledger))
+		return 1, nil
+	}
+
+	reverting, err := findAppliedMigrations(migrations, versions)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	if len(reverting) > 0 {
+		c, err := p.runMigrations(reverting, DOWN)
+		count += c
+		if err != nil {
+			return count, err
+		}
+	}
+	if len(pending) > 0 {
+		c, err := p.runMigrations(pending, UP)
+		count += c
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// migrateRedo reverts the last n applied migrations and then applies them again.
+func (p *preprocessor) migrateRedo(migrations []*migration, ledger []*ledgerEntry, n int) (int, error) {
+	versions := appliedVersionsDesc(ledger)
+	if n < len(versions) {
+		versions = versions[:n]
+	}
+
+	if len(versions) == 0 {
+		log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "no migrations to redo; database is at version 0")
+		return 1, nil
+	}
+
+	redoing, err := findAppliedMigrations(migrations, versions)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := p.runMigrations(redoing, DOWN)
+	if err != nil {
+		return count, err
+	}
+
+	reapplying := []*migration{}
+	for i := len(redoing) - 1; i >= 0; i-- {
+		reapplying = append(reapplying, redoing[i])
+	}
+
+	c, err := p.runMigrations(reapplying, UP)
+	return count + c, err
+}
+
+// migrateBaseline records the pending migrations up to and including the target version as
+// applied without executing them, for databases that were created before using migrations.
+func (p *preprocessor) migrateBaseline(migrations []*migration, ledger []*ledgerEntry) (int, error) {
+	target, err := p.getTargetVersion()
+	if err != nil {
+		return 0, err
+	}
+	if findMigration(migrations, target) == nil {
+		return 0, fmt.Errorf("migration version %d not found in %s", target, MIGRATIONS_REL_DIR)
+	}
+
+	count := 0
+	for _, m := range pendingMigrations(migrations, ledger) {
+		if m.version > target {
+			break
+		}
+
+		sum := ""
+		if m.up != nil {
+			content, err := readAll(m.up)
+			if err != nil {
+				return count, err
+			}
+			sum = checksum(content)
+		}
+
+		p.ctx.addInstructionWithParams(INSTR_MIGRATION_BASELINE, map[string]interface{}{
+			VERSION:  m.version,
+			TITLE:    m.title,
+			CHECKSUM: sum,
+		})
+		p.ctx.migrationLedger = append(p.ctx.migrationLedger, &ledgerEntry{version: m.version, title: m.title, checksum: sum})
+		count++
+	}
+
+	if count == 0 {
+		log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "no migrations to baseline; database is at version %d", currentVersion(ledger))
+		return 1, nil
+	}
+
+	return count, nil
+}
+
+func (p *preprocessor) runMigrations(migrations []*migration, direction string) (int, error) {
+	count := 0
+	for _, m := range migrations {
+		fr := m.up
+		if direction == DOWN {
+			fr = m.down
+		}
+		if fr == nil {
+			return count, fmt.Errorf("migration %d_%s has no %s file", m.version, m.title, direction)
+		}
+
+		c, err := p.makeMigrationInstructions(m, fr, direction)
+		count += c
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+func (p *preprocessor) makeMigrationInstructions(m *migration, fr *source.FileReader, direction string) (int, error) {
+	filePath := fr.Name
+	log.Debug("preprocessing migration %s", p.ctx.describeFile(filePath))
+	commandBytes, err := readAll(fr)
+	if err != nil {
+		return 0, err
+	}
+
+	cmds, _, _, err := parser.Parse(string(commandBytes))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", filePath, err.Error())
+	}
+
+	for _, cmd := range cmds {
+		if cmd.RootDef.Name == MIGRATE {
+			return 0, fmt.Errorf("%s: migrate commands cannot be used inside a migration", filePath)
+		}
+	}
+
+	params := map[string]interface{}{
+		VERSION:   m.version,
+		TITLE:     m.title,
+		DIRECTION: direction,
+		CHECKSUM:  checksum(commandBytes),
+	}
+
+	count := 1
+	p.ctx.addInstructionWithParams(INSTR_MIGRATION_BEGIN, params)
+	p.ctx.pushNesting()
+	p.ctx.addInstructionWithParams(INSTR_DDSL_FILE, map[string]interface{}{FILE_PATH: filePath})
+
+	// empty migrations, typically down migrations, are allowed
+	if len(cmds) > 0 {
+		c, err := preprocessBatch(p.ctx, cmds)
+		count += c
+		if err != nil {
+			p.ctx.popNesting()
+			return count, err
+		}
+	}
+
+	p.ctx.popNesting()
+	p.ctx.addInstruction(INSTR_DDSL_FILE_END)
+	p.ctx.addInstructionWithParams(INSTR_MIGRATION_END, params)
+
+	if direction == UP {
+		p.ctx.migrationLedger = append(p.ctx.migrationLedger, &ledgerEntry{
+			version:  m.version,
+			title:    m.title,
+			checksum: params[CHECKSUM].(string),
+		})
+	} else {
+		p.ctx.migrationLedger = removeLedgerEntry(p.ctx.migrationLedger, m.version)
+	}
+
+	return count, nil
+}
+
+// getMigrations returns the migrations found in the source repo sorted by version.
+func (p *preprocessor) getMigrations() ([]*migration, error) {
+	if err := p.ensureSourceDriverOpen(); err != nil {
+		return nil, err
+	}
+
+	p.ctx.addPattern(path.Join(MIGRATIONS_REL_DIR, MIGRATION_FILE_PATTERN))
+	fileReaders, err := p.readFiles(MIGRATIONS_REL_DIR, MIGRATION_FILE_PATTERN)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, fr := range fileReaders {
+		filePath := fr.Name
+		matches := migrationFileRegexp.FindStringSubmatch(path.Base(filePath))
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %s", filePath, err.Error())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, title: matches[2]}
+			byVersion[version] = m
+		} else if m.title != matches[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.title, matches[2])
+		}
+
+		switch matches[3] {
+		case UP:
+			m.up = fr
+		case DOWN:
+			m.down = fr
+		}
+	}
+
+	migrations := []*migration{}
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// getLedger returns the applied migrations. The ledger is read from the database once per batch
+// and then tracked in the context as migrations are preprocessed.
+func (p *preprocessor) getLedger() ([]*ledgerEntry, error) {
+	if p.ctx.migrationLedger != nil {
+		return p.ctx.migrationLedger, nil
+	}
+
+	dbDriver, err := dbdr.Open(p.ctx.DatbaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer dbDriver.Close()
+
+	p.ctx.dbDriver = dbDriver
+
+	ledger, err := readLedger(p.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.ctx.migrationLedger = ledger
+	return ledger, nil
+}
+
+func (p *preprocessor) preprocessMigrateStatus(migrations []*migration, ledger []*ledgerEntry) (int, error) {
+	versions := []int64{}
+	for _, m := range migrations {
+		versions = append(versions, m.version)
+	}
+	for _, e := range ledger {
+		if findMigration(migrations, e.version) == nil {
+			versions = append(versions, e.version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	data := [][]string{}
+	current := currentVersion(ledger)
+	for _, v := range versions {
+		m := findMigration(migrations, v)
+		e := findLedgerEntry(ledger, v)
+
+		var title, status, appliedAt, duration string
+		switch {
+		case e == nil && v < current:
+			title = m.title
+			status = "out of order"
+		case e == nil:
+			title = m.title
+			status = "pending"
+		case m == nil:
+			title = e.title
+			status = "missing"
+		case e.dirty:
+			title = m.title
+			status = "dirty"
+		default:
+			title = m.title
+			status = "applied"
+		}
+		if e != nil && !e.appliedAt.IsZero() {
+			appliedAt = e.appliedAt.Format("2006-01-02 15:04:05")
+			duration = (time.Duration(e.durationMs) * time.Millisecond).String()
+		}
+
+		data = append(data, []string{strconv.FormatInt(v, 10), title, status, appliedAt, duration})
+	}
+
+	p.makeListInstruction(MIGRATIONS, map[string]interface{}{MIGRATIONS: data})
+	return 1, nil
+}
+
+func (p *preprocessor) preprocessMigrateVerify(migrations []*migration, ledger []*ledgerEntry) (int, error) {
+	data, err := checksumDrift(migrations, ledger)
+	if err != nil {
+		return 0, err
+	}
+
+	p.makeListInstruction(CHECKSUMS, map[string]interface{}{MIGRATIONS: data})
+	return 1, nil
+}
+
+// ensureChecksumsMatch refuses to migrate when an applied migration has been edited
+// unless checksums are explicitly ignored.
+func (p *preprocessor) ensureChecksumsMatch(migrations []*migration, ledger []*ledgerEntry) error {
+	data, err := checksumDrift(migrations, ledger)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	changed := []string{}
+	for _, row := range data {
+		changed = append(changed, row[0]+"_"+row[1])
+	}
+
+	if p.ctx.IgnoreChecksums {
+		log.Warn("ignoring changes to applied migrations: %s", strings.Join(changed, ", "))
+		return nil
+	}
+
+	return fmt.Errorf("applied migrations have changed since they were applied: %s; run 'migrate verify' for details or ignore checksums to continue",
+		strings.Join(changed, ", "))
+}
+
+func (p *preprocessor) getNumberOfVersions() (int, error) {
+	if len(p.command.ExtArgs) == 0 {
+		return 1, nil
+	}
+	if len(p.command.ExtArgs) > 1 {
+		return 0, fmt.Errorf("only one number of versions may be provided")
+	}
+
+	n, err := strconv.Atoi(p.command.ExtArgs[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("number of versions must be a positive integer: '%s'", p.command.ExtArgs[0])
+	}
+	return n, nil
+}
+
+func (p *preprocessor) getTargetVersion() (int64, error) {
+	if len(p.command.ExtArgs) != 1 {
+		return 0, fmt.Errorf("exactly one version must be provided")
+	}
+
+	v, err := strconv.ParseInt(p.command.ExtArgs[0], 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("version must be a non-negative integer: '%s'", p.command.ExtArgs[0])
+	}
+	return v, nil
+}
+
+// pendingMigrations returns the migrations that have not been applied in ascending version order
+func pendingMigrations(migrations []*migration, ledger []*ledgerEntry) []*migration {
+	pending := []*migration{}
+	for _, m := range migrations {
+		if findLedgerEntry(ledger, m.version) == nil {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// orderedPendingMigrations returns the pending migrations after applying the out of order policy
+// to those older than the current version, which typically arrive when branches are merged.
+func (p *preprocessor) orderedPendingMigrations(migrations []*migration, ledger []*ledgerEntry) ([]*migration, error) {
+	current := currentVersion(ledger)
+	pending := []*migration{}
+	outOfOrder := []string{}
+	for _, m := range pendingMigrations(migrations, ledger) {
+		if m.version < current {
+			outOfOrder = append(outOfOrder, fmt.Sprintf("%d_%s", m.version, m.title))
+			if p.ctx.OutOfOrder != OUT_OF_ORDER_APPLY {
+				continue
+			}
+		}
+		pending = append(pending, m)
+	}
+
+	if len(outOfOrder) == 0 {
+		return pending, nil
+	}
+
+	switch p.ctx.OutOfOrder {
+	case "", OUT_OF_ORDER_REJECT:
+		return nil, fmt.Errorf("pending migrations are older than the current version %d: %s; apply or skip them with the out of order policy",
+			current, strings.Join(outOfOrder, ", "))
+	case OUT_OF_ORDER_WARN:
+		log.Warn("skipping pending migrations older than the current version %d: %s", current, strings.Join(outOfOrder, ", "))
+	case OUT_OF_ORDER_APPLY:
+		log.Warn("applying migrations older than the current version %d: %s", current, strings.Join(outOfOrder, ", "))
+	default:
+		return nil, fmt.Errorf("unknown out of order policy '%s'; must be %s, %s or %s",
+			p.ctx.OutOfOrder, OUT_OF_ORDER_REJECT, OUT_OF_ORDER_WARN, OUT_OF_ORDER_APPLY)
+	}
+
+	return pending, nil
+}
+
+func appliedVersionsDesc(ledger []*ledgerEntry) []int64 {
+	versions := []int64{}
+	for _, e := range ledger {
+		versions = append(versions, e.version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions
+}
+
+// findAppliedMigrations returns the migrations for applied versions, which must still exist in the source repo
+func findAppliedMigrations(migrations []*migration, versions []int64) ([]*migration, error) {
+	result := []*migration{}
+	for _, v := range versions {
+		m := findMigration(migrations, v)
+		if m == nil {
+			return nil, fmt.Errorf("migration %d is applied but its files were not found in %s", v, MIGRATIONS_REL_DIR)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func findMigration(migrations []*migration, version int64) *migration {
+	for _, m := range migrations {
+		if m.version == version {
+			return m
+		}
+	}
+	return nil
+}
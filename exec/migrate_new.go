@@ -0,0 +1,99 @@
+package exec
+
+import (
+	"fmt"
+	nurl "net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	VERSION_SCHEME_TIMESTAMP  = "timestamp"
+	VERSION_SCHEME_SEQUENTIAL = "sequential"
+
+	MIGRATION_TIMESTAMP_FORMAT = "20060102150405"
+)
+
+var migrationTitleRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationTime is a variable so tests can control timestamp versions
+var migrationTime = func() time.Time { return time.Now().UTC() }
+
+// preprocessMigrateNew adds instructions to write the up and down files of a new migration
+// to the migrations directory of a file:// source repo.
+func (p *preprocessor) preprocessMigrateNew(migrations []*migration) (int, error) {
+	if len(p.command.ExtArgs) != 1 {
+		return 0, fmt.Errorf("exactly one migration title must be provided")
+	}
+
+	title := strings.Trim(migrationTitleRegexp.ReplaceAllString(strings.ToLower(p.command.ExtArgs[0]), "_"), "_")
+	if len(title) == 0 {
+		return 0, fmt.Errorf("invalid migration title: '%s'", p.command.ExtArgs[0])
+	}
+
+	version, err := p.nextMigrationVersion(migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	if m := findMigration(migrations, version); m != nil {
+		return 0, fmt.Errorf("migration version %d is already used by %s", version, m.title)
+	}
+
+	dir, err := localSourcePath(p.ctx.SourceRepo)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, direction := range []string{UP, DOWN} {
+		fileName := fmt.Sprintf("%d_%s.%s.ddsl", version, title, direction)
+		p.ctx.addInstructionWithParams(INSTR_WRITE_FILE, map[string]interface{}{
+			FILE_PATH: path.Join(dir, MIGRATIONS_REL_DIR, fileName),
+			CONTENT:   fmt.Sprintf("# migrate %s: %s\n", direction, title),
+		})
+	}
+
+	return 2, nil
+}
+
+func (p *preprocessor) nextMigrationVersion(migrations []*migration) (int64, error) {
+	switch p.ctx.MigrationVersionScheme {
+	case "", VERSION_SCHEME_TIMESTAMP:
+		return strconv.ParseInt(migrationTime().Format(MIGRATION_TIMESTAMP_FORMAT), 10, 64)
+	case VERSION_SCHEME_SEQUENTIAL:
+		var latest int64
+		for _, m := range migrations {
+			if m.version > latest {
+				latest = m.version
+			}
+		}
+		return latest + 1, nil
+	}
+
+	return 0, fmt.Errorf("unknown migration version scheme '%s'; must be %s or %s",
+		p.ctx.MigrationVersionScheme, VERSION_SCHEME_TIMESTAMP, VERSION_SCHEME_SEQUENTIAL)
+}
+
+// localSourcePath returns the directory of a file:// source repo. Source drivers are read only,
+// so generated files can only be written to a local repo.
+func localSourcePath(sourceRepo string) (string, error) {
+	u, err := nurl.Parse(sourceRepo)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("files can only be generated in a file:// source repo")
+	}
+
+	p := u.Opaque
+	if len(p) == 0 {
+		p = u.Host + u.Path
+	}
+
+	return filepath.Abs(p)
+}
@@ -0,0 +1,352 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type migrateTestSpec struct {
+	command      string
+	applied      []int64
+	expectInstrs []*instruction
+}
+
+// migrationInstrs wraps the instructions of a migration file with the ledger instructions
+func migrationInstrs(version int64, title, direction, relPath string, instrs ...*instruction) []*instruction {
+	content, err := ioutil.ReadFile(filePath(relPath))
+	Expect(err).To(BeNil())
+	params := map[string]interface{}{VERSION: version, TITLE: title, DIRECTION: direction, CHECKSUM: checksum(content)}
+
+	result := []*instruction{
+		&instruction{INSTR_MIGRATION_BEGIN, params},
+		&instruction{INSTR_DDSL_FILE, map[string]interface{}{FILE_PATH: filePath(relPath)}},
+	}
+	result = append(result, instrs...)
+	return append(result,
+		&instruction{INSTR_DDSL_FILE_END, map[string]interface{}{}},
+		&instruction{INSTR_MIGRATION_END, params},
+	)
+}
+
+func makeLedger(versions ...int64) []*ledgerEntry {
+	ledger := []*ledgerEntry{}
+	for _, v := range versions {
+		ledger = append(ledger, &ledgerEntry{version: v})
+	}
+	return ledger
+}
+
+// makeMigrateTests is called from the spec because sourceDir is set in another file's init
+func makeMigrateTests() []migrateTestSpec {
+	createFooSchema := migrationInstrs(1, "create_foo_schema", UP, "migrations/1_create_foo_schema.up.ddsl",
+		&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "create schema foo_schema"}},
+		&instruction{INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath("schemas/foo_schema/schema.create.sql")}},
+	)
+	createFooType := migrationInstrs(2, "create_foo_type", UP, "migrations/2_create_foo_type.up.ddsl",
+		&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "create type foo_schema.foo_type"}},
+		&instruction{INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath("schemas/foo_schema/types/foo_type.create.sql")}},
+	)
+	seedFooSchema := migrationInstrs(3, "seed_foo_schema", UP, "migrations/3_seed_foo_schema.up.ddsl",
+		&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "seed schema foo_schema with foo_seed"}},
+		&instruction{INSTR_SQL_FILE, map[string]interface{}{
+			FILE_PATH:   filePath("schemas/foo_schema/seeds/foo_seed.sql"),
+			SCHEMA_NAME: "foo_schema",
+			SEED_NAME:   "foo_seed",
+		}},
+	)
+	unseedFooSchema := migrationInstrs(3, "seed_foo_schema", DOWN, "migrations/3_seed_foo_schema.down.ddsl")
+	dropFooType := migrationInstrs(2, "create_foo_type", DOWN, "migrations/2_create_foo_type.down.ddsl",
+		&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "drop type foo_schema.foo_type"}},
+		&instruction{INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath("schemas/foo_schema/types/foo_type.drop.sql")}},
+	)
+	dropFooSchema := migrationInstrs(1, "create_foo_schema", DOWN, "migrations/1_create_foo_schema.down.ddsl",
+		&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "drop schema foo_schema"}},
+		&instruction{INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath("schemas/foo_schema/schema.drop.sql")}},
+	)
+
+	return []migrateTestSpec{
+		{"migrate up", []int64{}, createFooSchema},
+		{"migrate up 1", []int64{}, createFooSchema},
+		{"migrate up 2", []int64{}, concatInstrs(createFooSchema, createFooType)},
+		{"migrate up 1", []int64{1}, createFooType},
+		{"migrate top", []int64{}, concatInstrs(createFooSchema, createFooType, seedFooSchema)},
+		{"migrate top", []int64{1, 2}, seedFooSchema},
+		{"migrate top", []int64{1, 2, 3}, []*instruction{}},
+		{"migrate up 1; migrate up 1", []int64{}, concatInstrs(
+			createFooSchema,
+			[]*instruction{{INSTR_DDSL, map[string]interface{}{COMMAND: "migrate up 1"}}},
+			createFooType,
+		)},
+		{"migrate down", []int64{1, 2, 3}, unseedFooSchema},
+		{"migrate down 2", []int64{1, 2, 3}, concatInstrs(unseedFooSchema, dropFooType)},
+		{"migrate bottom", []int64{1, 2}, concatInstrs(dropFooType, dropFooSchema)},
+		{"migrate bottom", []int64{}, []*instruction{}},
+		{"migrate to 2", []int64{}, concatInstrs(createFooSchema, createFooType)},
+		{"migrate to 1", []int64{1, 2, 3}, concatInstrs(unseedFooSchema, dropFooType)},
+		{"migrate to 0", []int64{1}, dropFooSchema},
+		{"migrate to 2", []int64{1, 2}, []*instruction{}},
+		{"migrate redo", []int64{1, 2}, concatInstrs(dropFooType, createFooType)},
+		{"migrate redo 2", []int64{1, 2, 3}, concatInstrs(unseedFooSchema, dropFooType, createFooType, seedFooSchema)},
+	}
+}
+
+var _ = ginkgo.Describe("migrate.go", func() {
+
+	ginkgo.It("preprocesses migrations", func() {
+		for _, mt := range makeMigrateTests() {
+			ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger(mt.applied...)}
+			cmds, _, _, err := parser.Parse(mt.command)
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			Expect(err).To(BeNil(), mt.command)
+			instrs := ctx.instructions[1:]
+			Expect(len(instrs)).To(Equal(len(mt.expectInstrs)), mt.command)
+			for i, instr := range instrs {
+				Expect(instr).To(Equal(mt.expectInstrs[i]), fmt.Sprintf("%d: %s", i, mt.command))
+			}
+		}
+	})
+
+	ginkgo.It("rejects applied migrations without files", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger(1, 2, 3, 4)}
+		cmds, _, _, err := parser.Parse("migrate down")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(HaveOccurred())
+	})
+
+	ginkgo.It("rejects a dirty database", func() {
+		ledger := makeLedger(1, 2)
+		ledger[1].dirty = true
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: ledger}
+		cmds, _, _, err := parser.Parse("migrate up")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(errors.Is(err, dbdr.ErrDatabaseDirty)).To(BeTrue())
+	})
+
+	ginkgo.It("reports migration status", func() {
+		ledger := makeLedger(1, 2, 4)
+		ledger[1].dirty = true
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: ledger}
+		cmds, _, _, err := parser.Parse("migrate status")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+		Expect(ctx.isListCommand()).To(BeTrue())
+
+		instrs := ctx.instructions[1:]
+		Expect(len(instrs)).To(Equal(1))
+		Expect(instrs[0].params[ITEM_TYPE]).To(Equal(MIGRATIONS))
+		Expect(instrs[0].params[MIGRATIONS]).To(Equal([][]string{
+			{"1", "create_foo_schema", "applied", "", ""},
+			{"2", "create_foo_type", "dirty", "", ""},
+			{"3", "seed_foo_schema", "out of order", "", ""},
+			{"4", "", "missing", "", ""},
+		}))
+	})
+
+	ginkgo.It("rejects changed applied migrations unless checksums are ignored", func() {
+		for _, ignore := range []bool{false, true} {
+			ledger := makeLedger(1, 2)
+			ledger[1].checksum = "stale"
+			ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: ledger, IgnoreChecksums: ignore}
+			cmds, _, _, err := parser.Parse("migrate up")
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			if ignore {
+				Expect(err).To(BeNil())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		}
+	})
+
+	ginkgo.It("verifies checksums of applied migrations", func() {
+		ledger := makeLedger(1, 2)
+		ledger[0].checksum = "stale"
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: ledger}
+		cmds, _, _, err := parser.Parse("migrate verify")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		instrs := ctx.instructions[1:]
+		Expect(len(instrs)).To(Equal(1))
+		Expect(instrs[0].params[ITEM_TYPE]).To(Equal(CHECKSUMS))
+		data := instrs[0].params[MIGRATIONS].([][]string)
+		Expect(len(data)).To(Equal(1))
+		Expect(data[0][0]).To(Equal("1"))
+		Expect(data[0][3]).To(Equal("stale"))
+	})
+
+	ginkgo.It("rejects unknown target versions", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger()}
+		cmds, _, _, err := parser.Parse("migrate to 9")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(HaveOccurred())
+	})
+
+	ginkgo.It("applies the out of order policy", func() {
+		createFooType := migrationInstrs(2, "create_foo_type", UP, "migrations/2_create_foo_type.up.ddsl",
+			&instruction{INSTR_DDSL, map[string]interface{}{COMMAND: "create type foo_schema.foo_type"}},
+			&instruction{INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath("schemas/foo_schema/types/foo_type.create.sql")}},
+		)
+
+		for _, policy := range []string{"", OUT_OF_ORDER_REJECT, OUT_OF_ORDER_WARN, OUT_OF_ORDER_APPLY} {
+			ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger(1, 3), OutOfOrder: policy}
+			cmds, _, _, err := parser.Parse("migrate top")
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+
+			switch policy {
+			case OUT_OF_ORDER_WARN:
+				Expect(err).To(BeNil())
+				Expect(len(ctx.instructions)).To(Equal(1))
+			case OUT_OF_ORDER_APPLY:
+				Expect(err).To(BeNil())
+				Expect(ctx.instructions[1:]).To(Equal(createFooType))
+			default:
+				Expect(err).To(HaveOccurred(), policy)
+			}
+		}
+
+		// reverting below the gap brings the migration back in order
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger(1, 3)}
+		cmds, _, _, err := parser.Parse("migrate to 2")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+	})
+
+	ginkgo.It("baselines migrations without running them", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger(1)}
+		cmds, _, _, err := parser.Parse("migrate baseline 2; migrate up")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+
+		content, err := ioutil.ReadFile(filePath("migrations/2_create_foo_type.up.ddsl"))
+		Expect(err).To(BeNil())
+		instrs := ctx.instructions[1:3]
+		Expect(instrs[0]).To(Equal(&instruction{INSTR_MIGRATION_BASELINE, map[string]interface{}{
+			VERSION:  int64(2),
+			TITLE:    "create_foo_type",
+			CHECKSUM: checksum(content),
+		}}))
+		Expect(instrs[1].params[COMMAND]).To(Equal("migrate up"))
+		Expect(ctx.instructions[3].params[VERSION]).To(Equal(int64(3)))
+	})
+
+	ginkgo.It("generates new migration files", func() {
+		defer func(t func() time.Time) { migrationTime = t }(migrationTime)
+		migrationTime = func() time.Time { return time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC) }
+
+		for scheme, version := range map[string]string{"": "20200304050607", VERSION_SCHEME_SEQUENTIAL: "4"} {
+			ctx := &Context{SourceRepo: "file://" + sourceDir, MigrationVersionScheme: scheme}
+			cmds, _, _, err := parser.Parse("migrate new Add-Bar-Table")
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			Expect(err).To(BeNil())
+
+			instrs := ctx.instructions[1:]
+			Expect(len(instrs)).To(Equal(2))
+			Expect(instrs[0].instrType).To(Equal(INSTR_WRITE_FILE))
+			Expect(instrs[0].params[FILE_PATH]).To(Equal(filePath("migrations/" + version + "_add_bar_table.up.ddsl")))
+			Expect(instrs[1].params[FILE_PATH]).To(Equal(filePath("migrations/" + version + "_add_bar_table.down.ddsl")))
+		}
+	})
+
+	ginkgo.It("rejects invalid number of versions", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, migrationLedger: makeLedger()}
+		cmds, _, _, err := parser.Parse("migrate up zero")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(HaveOccurred())
+	})
+
+	ginkgo.Context("with a database", func() {
+
+		var dir, databaseUrl string
+
+		tableNames := func() []string {
+			dbDriver, err := dbdr.Open(databaseUrl)
+			Expect(err).To(BeNil())
+			defer dbDriver.Close()
+			names, err := dbdr.QueryStrings(dbDriver, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+			Expect(err).To(BeNil())
+			return names
+		}
+
+		ginkgo.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "ddsl")
+			Expect(err).To(BeNil())
+			databaseUrl = "sqlite://" + filepath.Join(dir, "foo_database.db")
+		})
+
+		ginkgo.AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		ginkgo.It("reads a missing ledger without creating it", func() {
+			for _, command := range []string{"migrate status", "migrate verify", "migrate up"} {
+				ctx := &Context{SourceRepo: "file://" + sourceDir, DatbaseUrl: databaseUrl, DryRun: true}
+				cmds, _, _, err := parser.Parse(command)
+				Expect(err).To(BeNil())
+				_, err = preprocessBatch(ctx, cmds)
+				Expect(err).To(BeNil(), command)
+			}
+			Expect(tableNames()).To(BeEmpty())
+		})
+
+		ginkgo.It("fails on a ledger that cannot be read", func() {
+			dbDriver, err := dbdr.Open(databaseUrl)
+			Expect(err).To(BeNil())
+			Expect(dbDriver.Exec(strings.NewReader("CREATE TABLE ddsl_migrations (version BIGINT)"))).To(BeNil())
+			Expect(dbDriver.Close()).To(BeNil())
+
+			ctx := &Context{SourceRepo: "file://" + sourceDir, DatbaseUrl: databaseUrl, DryRun: true}
+			cmds, _, _, err := parser.Parse("migrate top")
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			Expect(err).To(HaveOccurred())
+		})
+
+		ginkgo.It("rejects migrations applied by another run", func() {
+			// both runs read the same ledger before either applies a migration
+			ctx := &Context{SourceRepo: "file://" + sourceDir, DatbaseUrl: databaseUrl}
+			cmds, _, _, err := parser.Parse("migrate up")
+			Expect(err).To(BeNil())
+			_, err = preprocessBatch(ctx, cmds)
+			Expect(err).To(BeNil())
+
+			other := &Context{SourceRepo: "file://" + sourceDir, DatbaseUrl: databaseUrl}
+			cmds, _, _, err = parser.Parse("migrate baseline 1")
+			Expect(err).To(BeNil())
+			Expect(ExecuteBatch(other, cmds)).To(BeNil())
+
+			err = (&processor{ctx: ctx}).process()
+			Expect(err).To(MatchError("migration 1_create_foo_schema is already applied; was it migrated by another run?"))
+		})
+	})
+})
+
+func concatInstrs(instrs ...[]*instruction) []*instruction {
+	result := []*instruction{}
+	for _, i := range instrs {
+		result = append(result, i...)
+	}
+	return result
+}
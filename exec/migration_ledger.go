@@ -0,0 +1,292 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/log"
+)
+
+// ledgerEntry is a row of the ddsl_migrations table
+type ledgerEntry struct {
+	version    int64
+	title      string
+	checksum   string
+	appliedAt  time.Time
+	durationMs int64
+	dirty      bool
+}
+
+func ensureMigrationTable(ctx *Context) error {
+	sql := `
+	CREATE TABLE IF NOT EXISTS ddsl_migrations (
+		version BIGINT PRIMARY KEY,
+		title TEXT,
+		checksum TEXT,
+		applied_at TIMESTAMP,
+		duration_ms BIGINT,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE
+	)`
+	return ctx.dbDriver.Exec(strings.NewReader(sql))
+}
+
+// readLedger returns the rows of the ddsl_migrations table. It does not create the table, so
+// that reading the ledger leaves the database unchanged; a missing table is an empty ledger.
+func readLedger(ctx *Context) ([]*ledgerEntry, error) {
+	exists, err := migrationTableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		log.Debug("no ddsl_migrations table")
+		return []*ledgerEntry{}, nil
+	}
+
+	sql := `
+	SELECT version, COALESCE(title, ''), COALESCE(checksum, ''), applied_at, COALESCE(duration_ms, 0), dirty
+	FROM ddsl_migrations
+	ORDER BY version`
+	rows, err := ctx.dbDriver.Query(strings.NewReader(sql))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*ledgerEntry{}
+	for rows.Next() {
+		e := &ledgerEntry{}
+		if err = rows.Scan(&e.version, &e.title, &e.checksum, &e.appliedAt, &e.durationMs, &e.dirty); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func migrationTableExists(ctx *Context) (bool, error) {
+	rows, err := ctx.dbDriver.Query(strings.NewReader(ctx.dbDriver.Dialect().TableExistsQuery), "ddsl_migrations")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	count := 0
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return false, err
+		}
+	}
+	return count > 0, rows.Err()
+}
+
+// lockLedger takes the database lock for the rest of the batch and reads the ledger inside it, so
+// that concurrent runs cannot both apply a migration preprocessed against the same ledger
+func (p *processor) lockLedger() error {
+	if p.ledgerLocked {
+		return nil
+	}
+
+	if err := p.ctx.dbDriver.Lock(); err != nil {
+		return err
+	}
+	p.ledgerLocked = true
+
+	if err := ensureMigrationTable(p.ctx); err != nil {
+		return err
+	}
+
+	ledger, err := readLedger(p.ctx)
+	if err != nil {
+		return err
+	}
+	p.ledger = ledger
+	return nil
+}
+
+func (p *processor) unlockLedger() {
+	if !p.ledgerLocked {
+		return
+	}
+	if err := p.ctx.dbDriver.Unlock(); err != nil {
+		log.Error("unable to release the database lock: %s", err)
+	}
+	p.ledgerLocked = false
+}
+
+// checkLedger fails if the ledger read inside the lock no longer allows the migration
+func (p *processor) checkLedger(version int64, title string, applied bool) error {
+	if (findLedgerEntry(p.ledger, version) != nil) == applied {
+		return nil
+	}
+	if applied {
+		return fmt.Errorf("migration %d_%s is no longer applied; was it migrated by another run?", version, title)
+	}
+	return fmt.Errorf("migration %d_%s is already applied; was it migrated by another run?", version, title)
+}
+
+func (p *processor) beginMigration(instr *instruction) error {
+	version := instr.params[VERSION].(int64)
+	title := instr.params[TITLE].(string)
+	direction := instr.params[DIRECTION].(string)
+
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "migrating %s %d_%s", direction, version, title)
+	p.migrationStart = time.Now()
+	if p.ctx.DryRun {
+		return nil
+	}
+
+	if err := p.lockLedger(); err != nil {
+		return err
+	}
+	if err := p.checkLedger(version, title, direction == DOWN); err != nil {
+		return err
+	}
+
+	// the migration stays dirty if it fails outside of a transaction
+	if direction == UP {
+		p.ledger = append(p.ledger, &ledgerEntry{version: version, title: title, dirty: true})
+		sql := `
+		INSERT INTO ddsl_migrations (version, title, checksum, applied_at, dirty)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, TRUE)`
+		return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), version, title, instr.params[CHECKSUM].(string))
+	}
+
+	sql := "UPDATE ddsl_migrations SET dirty = TRUE WHERE version = ?"
+	return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), version)
+}
+
+func (p *processor) endMigration(instr *instruction) error {
+	version := instr.params[VERSION].(int64)
+	title := instr.params[TITLE].(string)
+	direction := instr.params[DIRECTION].(string)
+	duration := time.Since(p.migrationStart)
+
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "completed migrating %s %d_%s in %s", direction, version, title, duration)
+	if p.ctx.DryRun {
+		return nil
+	}
+
+	if direction == UP {
+		sql := "UPDATE ddsl_migrations SET dirty = FALSE, duration_ms = ? WHERE version = ?"
+		return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), duration.Milliseconds(), version)
+	}
+
+	p.ledger = removeLedgerEntry(p.ledger, version)
+	sql := "DELETE FROM ddsl_migrations WHERE version = ?"
+	return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), version)
+}
+
+func (p *processor) baselineMigration(instr *instruction) error {
+	version := instr.params[VERSION].(int64)
+	title := instr.params[TITLE].(string)
+
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "marking migration %d_%s as applied", version, title)
+	if p.ctx.DryRun {
+		return nil
+	}
+
+	if err := p.lockLedger(); err != nil {
+		return err
+	}
+	if err := p.checkLedger(version, title, false); err != nil {
+		return err
+	}
+
+	p.ledger = append(p.ledger, &ledgerEntry{version: version, title: title})
+	sql := `
+	INSERT INTO ddsl_migrations (version, title, checksum, applied_at, duration_ms, dirty)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP, 0, FALSE)`
+	return p.ctx.dbDriver.Exec(strings.NewReader(p.ctx.bind(sql)), version, title, instr.params[CHECKSUM].(string))
+}
+
+func (p *processor) renderMigrationStatus(instr *instruction) error {
+	header := []string{"Version", "Title", "Status", "Applied At", "Duration"}
+	return p.listOutput(header, instr.params[MIGRATIONS].([][]string))
+}
+
+func ensureNotDirty(ledger []*ledgerEntry) error {
+	for _, e := range ledger {
+		if e.dirty {
+			return fmt.Errorf("%w: migration %d_%s did not complete; repair the database and clear the dirty flag in ddsl_migrations",
+				dbdr.ErrDatabaseDirty, e.version, e.title)
+		}
+	}
+	return nil
+}
+
+func (p *processor) renderChecksumDrift(instr *instruction) error {
+	header := []string{"Version", "Title", "File", "Applied Checksum", "Current Checksum"}
+	data := instr.params[MIGRATIONS].([][]string)
+	if err := p.listOutput(header, data); err != nil {
+		return err
+	}
+
+	// a non-zero exit lets CI fail when applied migrations have been edited
+	if len(data) > 0 {
+		return fmt.Errorf("%d applied migration(s) changed since they were applied", len(data))
+	}
+	return nil
+}
+
+// checksumDrift returns a row for each applied migration whose up file no longer matches
+// the checksum recorded when it was applied. Entries without a checksum are not verified.
+func checksumDrift(migrations []*migration, ledger []*ledgerEntry) ([][]string, error) {
+	data := [][]string{}
+	for _, e := range ledger {
+		m := findMigration(migrations, e.version)
+		if m == nil || m.up == nil || len(e.checksum) == 0 {
+			continue
+		}
+
+		content, err := readAll(m.up)
+		if err != nil {
+			return nil, err
+		}
+
+		current := checksum(content)
+		if current != e.checksum {
+			data = append(data, []string{strconv.FormatInt(e.version, 10), m.title, m.up.Name, e.checksum, current})
+		}
+	}
+	return data, nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func findLedgerEntry(ledger []*ledgerEntry, version int64) *ledgerEntry {
+	for _, e := range ledger {
+		if e.version == version {
+			return e
+		}
+	}
+	return nil
+}
+
+func removeLedgerEntry(ledger []*ledgerEntry, version int64) []*ledgerEntry {
+	result := []*ledgerEntry{}
+	for _, e := range ledger {
+		if e.version != version {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func currentVersion(ledger []*ledgerEntry) int64 {
+	var current int64
+	for _, e := range ledger {
+		if e.version > current {
+			current = e.version
+		}
+	}
+	return current
+}
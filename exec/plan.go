@@ -0,0 +1,135 @@
+package exec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// instructionNames name the instruction types in plans
+var instructionNames = map[InstructionType]string{
+	INSTR_DDSL:               "ddsl",
+	INSTR_SQL_FILE:           "sql file",
+	INSTR_CSV_FILE:           "csv file",
+	INSTR_DDSL_FILE:          "ddsl file",
+	INSTR_SH_FILE:            "sh file",
+	INSTR_DDSL_FILE_END:      "ddsl file end",
+	INSTR_SH_SCRIPT:          "sh script",
+	INSTR_BEGIN:              "begin",
+	INSTR_COMMIT:             "commit",
+	INSTR_ROLLBACK:           "rollback",
+	INSTR_SQL_SCRIPT:         "sql script",
+	INSTR_LIST:               "list",
+	INSTR_MIGRATION_BEGIN:    "migration begin",
+	INSTR_MIGRATION_END:      "migration end",
+	INSTR_WRITE_FILE:         "write file",
+	INSTR_MIGRATION_BASELINE: "migration baseline",
+	INSTR_EXTRACT:            "extract",
+}
+
+// planItemRegexp matches the schema and item of a file in the source repo
+var planItemRegexp = regexp.MustCompile(`^schemas/([^/]+)/(?:(?:tables|views|functions|procedures)/([^/]+)/|types/([^/]+?)\.)?`)
+
+func (t InstructionType) String() string {
+	if name, ok := instructionNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("instruction %d", int(t))
+}
+
+// renderPlan lists the instructions of the batch in the order the processor would execute them,
+// including the transaction wrapping them when transactions are automatic
+func (p *processor) renderPlan() error {
+	header := []string{"Step", "Nesting", "Transaction", "Instruction", "Command", "File", "Schema Name", "Item Name", "Details"}
+	data := planInstructions(p.ctx)
+	return p.listOutput(header, data)
+}
+
+// planInstructions returns a row per instruction of the batch
+func planInstructions(ctx *Context) [][]string {
+	instructions := ctx.instructions
+
+	// the processor wraps the batch in a transaction unless it only lists
+	autoTransaction := ctx.AutoTransaction && ctx.nonList
+	if autoTransaction {
+		instructions = append([]*instruction{{INSTR_BEGIN, map[string]interface{}{}}}, instructions...)
+		instructions = append(instructions, &instruction{INSTR_COMMIT, map[string]interface{}{}})
+	}
+
+	data := [][]string{}
+	nesting := 0
+	inTransaction := false
+	for i, instr := range instructions {
+		switch instr.instrType {
+		case INSTR_DDSL_FILE_END:
+			nesting--
+		case INSTR_BEGIN:
+			inTransaction = true
+		}
+
+		row := planInstruction(ctx, instr)
+		transaction := ""
+		if inTransaction {
+			transaction = "yes"
+			if autoTransaction && (i == 0 || i == len(instructions)-1) {
+				transaction = "auto"
+			}
+		}
+		data = append(data, append([]string{strconv.Itoa(i + 1), strconv.Itoa(nesting), transaction}, row...))
+
+		switch instr.instrType {
+		case INSTR_DDSL_FILE:
+			nesting++
+		case INSTR_COMMIT, INSTR_ROLLBACK:
+			inTransaction = false
+		}
+	}
+	return data
+}
+
+// planInstruction returns the instruction, command, file, schema name, item name and details of
+// an instruction
+func planInstruction(ctx *Context, instr *instruction) []string {
+	command, file, schemaName, itemName, details := "", "", "", "", ""
+
+	if c, ok := instr.params[COMMAND].(string); ok {
+		command = c
+	}
+
+	if f, ok := instr.params[FILE_PATH].(string); ok {
+		file = ctx.relativePath(f)
+		if m := planItemRegexp.FindStringSubmatch(file); m != nil {
+			schemaName, itemName = m[1], m[2]+m[3]
+		}
+	}
+	if s, ok := instr.params[SCHEMA_NAME].(string); ok {
+		schemaName = s
+	}
+	if t, ok := instr.params[TABLE_NAME].(string); ok {
+		itemName = t
+	}
+
+	switch instr.instrType {
+	case INSTR_SQL_SCRIPT:
+		details = instr.params[SQL].(string)
+	case INSTR_SH_SCRIPT:
+		// the command is a shell command rather than a DDSL command
+		command = ""
+		details = strings.Join(append([]string{instr.params[COMMAND].(string)}, instr.params[ARGS].([]string)...), " ")
+	case INSTR_LIST:
+		details = instr.params[ITEM_TYPE].(string)
+	case INSTR_MIGRATION_BEGIN, INSTR_MIGRATION_END:
+		details = fmt.Sprintf("%s %d_%s", instr.params[DIRECTION], instr.params[VERSION], instr.params[TITLE])
+	case INSTR_MIGRATION_BASELINE:
+		details = fmt.Sprintf("%d_%s", instr.params[VERSION], instr.params[TITLE])
+	case INSTR_EXTRACT:
+		if schemaNames, ok := instr.params[SCHEMA_NAMES].([]string); ok {
+			details = strings.Join(schemaNames, ",")
+		} else if except := instr.params[EXCLUDED_SCHEMA_NAMES].([]string); len(except) > 0 {
+			details = "except " + strings.Join(except, ",")
+		}
+	}
+
+	return []string{instr.instrType.String(), command, file, schemaName, itemName, details}
+}
@@ -0,0 +1,120 @@
+package exec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	dbdr "github.com/nrfta/ddsl/drivers/database"
+	"github.com/nrfta/ddsl/parser"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("plan.go", func() {
+
+	plan := func(command string, autoTx bool) [][]string {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, AutoTransaction: autoTx, Plan: true}
+		cmds, _, _, err := parser.Parse(command)
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+		return planInstructions(ctx)
+	}
+
+	ginkgo.It("plans nested instructions in an automatic transaction", func() {
+		Expect(plan("seed schema foo_schema", true)).To(Equal([][]string{
+			{"1", "0", "auto", "begin", "", "", "", "", ""},
+			{"2", "0", "yes", "ddsl", "seed schema foo_schema", "", "", "", ""},
+			{"3", "0", "yes", "ddsl file", "", "schemas/foo_schema/seeds/schema.ddsl", "foo_schema", "", ""},
+			{"4", "1", "yes", "ddsl", "seed tables in foo_schema", "", "", "", ""},
+			{"5", "1", "yes", "csv file", "", "schemas/foo_schema/tables/bar_table/seeds/table.csv", "foo_schema", "bar_table", ""},
+			{"6", "1", "yes", "csv file", "", "schemas/foo_schema/tables/baz_table/seeds/table.csv", "foo_schema", "baz_table", ""},
+			{"7", "1", "yes", "csv file", "", "schemas/foo_schema/tables/foo_table/seeds/table.csv", "foo_schema", "foo_table", ""},
+			{"8", "0", "yes", "ddsl file end", "", "", "", "", ""},
+			{"9", "0", "auto", "commit", "", "", "", "", ""},
+		}))
+	})
+
+	ginkgo.It("plans without executing", func() {
+		// sql statements are otherwise executed while preprocessing
+		Expect(plan("sql VACUUM; create type foo_schema.foo_type", false)).To(Equal([][]string{
+			{"1", "0", "", "ddsl", "sql VACUUM", "", "", "", ""},
+			{"2", "0", "", "sql script", "", "", "", "", "VACUUM"},
+			{"3", "0", "", "ddsl", "create type foo_schema.foo_type", "", "", "", ""},
+			{"4", "0", "", "sql file", "", "schemas/foo_schema/types/foo_type.create.sql", "foo_schema", "foo_type", ""},
+		}))
+	})
+
+	ginkgo.It("plans migrations without changing the database", func() {
+		dir, err := ioutil.TempDir("", "ddsl")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		databaseUrl := "sqlite://" + filepath.Join(dir, "foo_database.db")
+
+		ctx := &Context{SourceRepo: "file://" + sourceDir, DatbaseUrl: databaseUrl, Plan: true}
+		cmds, _, _, err := parser.Parse("migrate up 2")
+		Expect(err).To(BeNil())
+		_, err = preprocessBatch(ctx, cmds)
+		Expect(err).To(BeNil())
+		Expect(planInstructions(ctx)).To(Equal([][]string{
+			{"1", "0", "", "ddsl", "migrate up 2", "", "", "", ""},
+			{"2", "0", "", "migration begin", "", "", "", "", "up 1_create_foo_schema"},
+			{"3", "0", "", "ddsl file", "", "migrations/1_create_foo_schema.up.ddsl", "", "", ""},
+			{"4", "1", "", "ddsl", "create schema foo_schema", "", "", "", ""},
+			{"5", "1", "", "sql file", "", "schemas/foo_schema/schema.create.sql", "foo_schema", "", ""},
+			{"6", "0", "", "ddsl file end", "", "", "", "", ""},
+			{"7", "0", "", "migration end", "", "", "", "", "up 1_create_foo_schema"},
+			{"8", "0", "", "migration begin", "", "", "", "", "up 2_create_foo_type"},
+			{"9", "0", "", "ddsl file", "", "migrations/2_create_foo_type.up.ddsl", "", "", ""},
+			{"10", "1", "", "ddsl", "create type foo_schema.foo_type", "", "", "", ""},
+			{"11", "1", "", "sql file", "", "schemas/foo_schema/types/foo_type.create.sql", "foo_schema", "foo_type", ""},
+			{"12", "0", "", "ddsl file end", "", "", "", "", ""},
+			{"13", "0", "", "migration end", "", "", "", "", "up 2_create_foo_type"},
+		}))
+
+		dbDriver, err := dbdr.Open(databaseUrl)
+		Expect(err).To(BeNil())
+		defer dbDriver.Close()
+		tableNames, err := dbdr.QueryStrings(dbDriver, "SELECT name FROM sqlite_master WHERE type = 'table'")
+		Expect(err).To(BeNil())
+		Expect(tableNames).To(BeEmpty())
+	})
+
+	ginkgo.It("renders plans as JSON", func() {
+		ctx := &Context{SourceRepo: "file://" + sourceDir, OutputFormat: OUTPUT_JSON, Plan: true}
+		cmds, _, _, err := parser.Parse("create type foo_schema.foo_type")
+		Expect(err).To(BeNil())
+
+		output := captureStdout(func() {
+			Expect(ExecuteBatch(ctx, cmds)).To(BeNil())
+		})
+
+		plan := []map[string]string{}
+		Expect(json.Unmarshal(output, &plan)).To(BeNil())
+		Expect(plan).To(Equal([]map[string]string{
+			{"Step": "1", "Nesting": "0", "Transaction": "", "Instruction": "ddsl", "Command": "create type foo_schema.foo_type",
+				"File": "", "Schema Name": "", "Item Name": "", "Details": ""},
+			{"Step": "2", "Nesting": "0", "Transaction": "", "Instruction": "sql file", "Command": "",
+				"File": "schemas/foo_schema/types/foo_type.create.sql", "Schema Name": "foo_schema", "Item Name": "foo_type", "Details": ""},
+		}))
+	})
+})
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(fn func()) []byte {
+	r, w, err := os.Pipe()
+	Expect(err).To(BeNil())
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	Expect(w.Close()).To(BeNil())
+
+	output, err := ioutil.ReadAll(r)
+	Expect(err).To(BeNil())
+	return output
+}
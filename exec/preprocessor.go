@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/forestgiant/sliceutil"
 	"github.com/nrfta/ddsl/drivers/source"
+	"github.com/nrfta/ddsl/drivers/source/overlay"
 	"github.com/nrfta/ddsl/log"
 	"github.com/nrfta/ddsl/parser"
+	"io/ioutil"
 	"path"
 	"sort"
 	"strings"
@@ -21,6 +23,9 @@ const (
 	GRANT            string = "grant"
 	REVOKE           string = "revoke"
 	LIST             string = "list"
+	DIFF             string = "diff"
+	EXTRACT          string = "extract"
+	GENERATE         string = "generate"
 	DATABASE         string = "database"
 	DATABASE_PRIVS   string = "database-privs"
 	EXTENSIONS       string = "extensions"
@@ -48,6 +53,7 @@ const (
 	PROCEDURE        string = "procedure"
 	PROCEDURE_PRIVS  string = "procedure-privs"
 	INDEXES          string = "indexes"
+	COLUMNS          string = "columns"
 	CONSTRAINTS      string = "constraints"
 	PRIVILEGES       string = "privileges"
 	TRIGGERS         string = "triggers"
@@ -56,6 +62,20 @@ const (
 	CMD              string = "cmd"
 	IN               string = "in"
 	EXCEPT_IN        string = "except in"
+	UP               string = "up"
+	DOWN             string = "down"
+	TOP              string = "top"
+	BOTTOM           string = "bottom"
+	TO               string = "to"
+	REDO             string = "redo"
+	NEW              string = "new"
+	BASELINE         string = "baseline"
+	STATUS           string = "status"
+	VERIFY           string = "verify"
+	CHECKSUMS        string = "checksums"
+	MIGRATIONS       string = "migrations"
+	DROPS            string = "drops"
+	CHECK            string = "check"
 
 	// param keys
 	FILE_PATH    string = "file_path"
@@ -63,9 +83,19 @@ const (
 	ARGS         string = "args"
 	SCHEMA_NAME  string = "schema_name"
 	SCHEMA_NAMES string = "schema_names"
+	ITEM_TYPES   string = "item_types"
+	SOURCE_ITEMS string = "source_items"
 	TABLE_NAME   string = "table_name"
+	TABLE_NAMES  string = "table_names"
 	SEED_NAME    string = "seed_name"
 	ITEM_TYPE    string = "item_type"
+	VERSION      string = "version"
+	TITLE        string = "title"
+	DIRECTION    string = "direction"
+	CHECKSUM     string = "checksum"
+	CONTENT      string = "content"
+
+	EXCLUDED_SCHEMA_NAMES string = "excluded_schema_names"
 )
 
 var pathPatterns = map[string]string{
@@ -126,6 +156,11 @@ const (
 	INSTR_ROLLBACK
 	INSTR_SQL_SCRIPT
 	INSTR_LIST
+	INSTR_MIGRATION_BEGIN
+	INSTR_MIGRATION_END
+	INSTR_WRITE_FILE
+	INSTR_MIGRATION_BASELINE
+	INSTR_EXTRACT
 )
 
 type instruction struct {
@@ -205,11 +240,6 @@ func makeInstructions(ctx *Context, cmd *parser.Command) (int, error) {
 		ctx:     ctx,
 		command: cmd,
 	}
-	defer func() {
-		if p.sourceDriver != nil {
-			p.sourceDriver.Close()
-		}
-	}()
 
 	count, err := p.preprocess()
 	if err != nil {
@@ -241,12 +271,18 @@ func (p *preprocessor) preprocess() (int, error) {
 		count, err = p.preprocessGrantOrRevoke()
 	case SEED:
 		count, err = p.preprocessSeed()
-	//case MIGRATE:
-	//	count, err = p.preprocessMigrate()
+	case MIGRATE:
+		count, err = p.preprocessMigrate()
 	case SQL:
 		count, err = p.preprocessSql()
 	case LIST:
 		count, err = p.preprocessList()
+	case DIFF:
+		count, err = p.preprocessDiff()
+	case EXTRACT:
+		count, err = p.preprocessExtract()
+	case GENERATE:
+		count, err = p.preprocessGenerateDrops()
 	default:
 		return 0, fmt.Errorf("unknown command")
 	}
@@ -263,6 +299,12 @@ func (p *preprocessor) preprocessSql() (int, error) {
 		return 0, fmt.Errorf("the sql command requires one argument")
 	}
 
+	// the statement is executed while preprocessing, which planning must not do
+	if p.ctx.Plan {
+		p.ctx.addInstructionWithParams(INSTR_SQL_SCRIPT, map[string]interface{}{SQL: p.command.ExtArgs[0]})
+		return 1, nil
+	}
+
 	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing SQL statement")
 	if p.ctx.DryRun {
 		return 1, nil
@@ -291,21 +333,45 @@ func (p *preprocessor) makeFileInstructions(pathPattern string) (int, error) {
 	for _, d := range dirs {
 		p.ctx.addPattern(path.Join(d, filePattern))
 
-		filePaths, err := p.sourceDriver.ReadFiles(d, filePattern)
+		fileReaders, err := p.readFiles(d, filePattern)
 		if err != nil {
 			return 0, err
 		}
 
-		count += len(filePaths)
+		count += len(fileReaders)
 
-		for _, filePath := range filePaths {
-			log.Debug("preprocessing %s", filePath)
-			p.ctx.addInstructionWithParams(INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: filePath})
+		for _, fr := range fileReaders {
+			log.Debug("preprocessing %s", p.ctx.describeFile(fr.Name))
+			p.ctx.addInstructionWithParams(INSTR_SQL_FILE, map[string]interface{}{FILE_PATH: fr.Name})
 		}
 	}
 	return count, nil
 }
 
+// readFiles reads files from the source driver and remembers them so the processor can open them by name
+func (p *preprocessor) readFiles(relativeDir, fileNamePattern string) ([]*source.FileReader, error) {
+	fileReaders, err := p.sourceDriver.ReadFiles(relativeDir, fileNamePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fr := range fileReaders {
+		p.ctx.addFile(fr, path.Join(relativeDir, path.Base(fr.Name)))
+	}
+	return fileReaders, nil
+}
+
+// readAll returns the content of a file read from a source driver
+func readAll(fr *source.FileReader) ([]byte, error) {
+	r, err := fr.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
 func (p *preprocessor) makeListInstruction(itemType string, params map[string]interface{}) {
 	params[ITEM_TYPE] = itemType
 	p.ctx.addInstructionWithParams(INSTR_LIST, params)
@@ -424,19 +490,40 @@ func (p *preprocessor) ensureSourceDriverOpen() error {
 		return nil
 	}
 
-	url := strings.TrimRight(p.ctx.SourceRepo, "/")
+	url := p.ctx.SourceRepo
+	if layers, ok := overlay.Layers(url); ok {
+		// each layer has its own ref, so there is no single ref to override
+		if p.command.Ref != nil {
+			return fmt.Errorf("refs are not supported with an overlay DDSL_SOURCE; add them to its layers")
+		}
+		if len(layers) == 0 {
+			return fmt.Errorf("overlay DDSL_SOURCE has no layers")
+		}
 
-	i := strings.LastIndex(url, "/")
-	if i == -1 {
-		return fmt.Errorf("database name must be last element of DDSL_SOURCE")
-	}
-	p.databaseName = url[i+1:]
+		// the database name is taken from the top layer
+		databaseName, _, err := splitSourceURL(layers[len(layers)-1])
+		if err != nil {
+			return err
+		}
+		p.databaseName = databaseName
+	} else {
+		databaseName, ref, err := splitSourceURL(url)
+		if err != nil {
+			return err
+		}
+		p.databaseName = databaseName
 
-	ref := p.command.Ref
-	if ref != nil {
-		url += "#" + *ref
+		// a ref on the command overrides one in the source URL
+		url = strings.TrimRight(strings.TrimSuffix(url, "#"+ref), "/")
+		if p.command.Ref != nil {
+			ref = *p.command.Ref
+		}
+		if len(ref) > 0 {
+			url += "#" + ref
+		}
 	}
-	sourceDriver, err := source.Open(url)
+
+	sourceDriver, err := p.ctx.openSourceDriver(url)
 	if err != nil {
 		return err
 	}
@@ -446,6 +533,21 @@ func (p *preprocessor) ensureSourceDriverOpen() error {
 	return nil
 }
 
+// splitSourceURL returns the database name, which is the last element of a source URL, and the
+// ref of the URL if it has one
+func splitSourceURL(url string) (databaseName, ref string, err error) {
+	if i := strings.Index(url, "#"); i > -1 {
+		url, ref = url[:i], url[i+1:]
+	}
+	url = strings.TrimRight(url, "/")
+
+	i := strings.LastIndex(url, "/")
+	if i == -1 {
+		return "", "", fmt.Errorf("database name must be last element of DDSL_SOURCE")
+	}
+	return url[i+1:], ref, nil
+}
+
 func (p *preprocessor) preprocessKey(patternKey string, params ...interface{}) (int, error) {
 	switch {
 	case len(p.createOrDrop) > 0:
@@ -565,6 +667,10 @@ func (p *preprocessor) preprocessSchemaItems(itemType string) (int, error) {
 		return 0, err
 	}
 
+	if dependencyOrderedKeys[itemType] && len(p.createOrDrop) > 0 {
+		return p.preprocessOrderedSchemaItems(itemType, schemaNames)
+	}
+
 	count := 0
 
 	// before dropping any tables, drop any foreign keys
@@ -636,6 +742,22 @@ func (p *preprocessor) preprocessListSchemaItems(itemType string) (int, error) {
 	return 1, nil
 }
 
+func (p *preprocessor) preprocessListTableItems(itemType string) (int, error) {
+	if p.command.Clause != "on" {
+		return 0, fmt.Errorf("comma-delimited list of tables is required")
+	}
+
+	for _, n := range p.command.ExtArgs {
+		if _, _, err := parseSchemaItemName(n); err != nil {
+			return 0, err
+		}
+	}
+
+	p.makeListInstruction(itemType, map[string]interface{}{TABLE_NAMES: p.command.ExtArgs})
+
+	return 1, nil
+}
+
 func parseSchemaItemName(item string) (schemaName string, tableOrViewName string, err error) {
 	if len(item) == 0 {
 		return "", "", fmt.Errorf("empty table or view name provided; check for trailing comma or space after comma in list arg")
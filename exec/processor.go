@@ -6,11 +6,17 @@ import (
 	"github.com/nrfta/ddsl/log"
 	"github.com/nrfta/ddsl/util"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type processor struct {
-	ctx *Context
+	ctx            *Context
+	migrationStart time.Time
+	ledger         []*ledgerEntry
+	ledgerLocked   bool
 }
 
 type getSchemaItemsFn func(string) ([]*dbdr.SchemaItemInfo, error)
@@ -23,6 +29,7 @@ func (p *processor) process() error {
 	defer dbDriver.Close()
 
 	p.ctx.dbDriver = dbDriver
+	defer p.unlockLedger()
 
 	err = ensureAuditTable(p.ctx)
 	if err != nil {
@@ -74,9 +81,19 @@ func (p *processor) processInstructions() error {
 		case INSTR_LIST:
 			err = p.executeList(instr)
 		case INSTR_DDSL_FILE:
-			log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing DDSL file %s", instr.params[FILE_PATH].(string))
+			log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing DDSL file %s", p.ctx.describeFile(instr.params[FILE_PATH].(string)))
 		case INSTR_DDSL_FILE_END:
 			log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "completed executing DDSL file")
+		case INSTR_MIGRATION_BEGIN:
+			err = p.beginMigration(instr)
+		case INSTR_MIGRATION_END:
+			err = p.endMigration(instr)
+		case INSTR_MIGRATION_BASELINE:
+			err = p.baselineMigration(instr)
+		case INSTR_WRITE_FILE:
+			err = p.writeFile(instr)
+		case INSTR_EXTRACT:
+			err = p.extract(instr)
 		}
 
 		if err != nil {
@@ -143,18 +160,44 @@ func (p *processor) rollbackTransaction() error {
 
 func (p *processor) executeSQLFile(instr *instruction) error {
 	filePath := instr.params[FILE_PATH].(string)
-	fr, err := os.Open(filePath)
+	fr, err := p.ctx.openFile(filePath)
 	if err != nil {
 		return err
 	}
+	defer fr.Close()
 
-	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing SQL file %s", filePath)
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing SQL file %s", p.ctx.describeFile(filePath))
 	if !p.ctx.DryRun {
 		return p.ctx.dbDriver.Exec(fr)
 	}
 	return nil
 }
 
+func (p *processor) writeFile(instr *instruction) error {
+	return p.createFile(instr.params[FILE_PATH].(string), instr.params[CONTENT].(string))
+}
+
+func (p *processor) createFile(filePath, content string) error {
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "creating file %s", filePath)
+	if p.ctx.DryRun {
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// never overwrite an existing file
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	return err
+}
+
 func (p *processor) executeSQLScript(instr *instruction) error {
 	sql := instr.params[SQL].(string)
 
@@ -169,7 +212,7 @@ func (p *processor) executeSQLScript(instr *instruction) error {
 func (p *processor) executeShellScriptFile(instr *instruction) error {
 	filePath := instr.params[FILE_PATH].(string)
 
-	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing shell script file %s", filePath)
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "executing shell script file %s", p.ctx.describeFile(filePath))
 	if !p.ctx.DryRun {
 		out, err := util.OSExec("sh", filePath)
 		if err != nil {
@@ -208,10 +251,16 @@ func (p *processor) importCSV(instr *instruction) error {
 	schemaName := instr.params[SCHEMA_NAME].(string)
 	tableName := instr.params[TABLE_NAME].(string)
 
-	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "importing CSV %s", filePath)
+	log.Log(levelOrDryRun(p.ctx, log.LEVEL_INFO), "importing CSV %s", p.ctx.describeFile(filePath))
 	if !p.ctx.DryRun {
+		fr, err := p.ctx.openFile(filePath)
+		if err != nil {
+			return err
+		}
+		defer fr.Close()
+
 		// TODO: provide options for delimiter and header
-		output, err := p.ctx.dbDriver.ImportCSV(filePath, schemaName, tableName, ",", true)
+		output, err := p.ctx.dbDriver.ImportCSV(fr, schemaName, tableName, ",", true)
 		if err != nil {
 			return err
 		}
@@ -279,6 +328,32 @@ func (p *processor) executeList(instr *instruction) error {
 		}
 		return p.listOutput(header, data)
 
+	case ROLES:
+		roles, err := p.ctx.dbDriver.Roles()
+		if err != nil {
+			return err
+		}
+		header := []string{"Role Name", "Login", "Superuser", "Create DB", "Create Role", "Inherit", "Replication", "Member Of"}
+		data := [][]string{}
+		for _, r := range roles {
+			data = append(data, []string{r.Name, strconv.FormatBool(r.Login), strconv.FormatBool(r.Superuser),
+				strconv.FormatBool(r.CreateDB), strconv.FormatBool(r.CreateRole), strconv.FormatBool(r.Inherit),
+				strconv.FormatBool(r.Replication), strings.Join(r.MemberOf, ", ")})
+		}
+		return p.listOutput(header, data)
+
+	case EXTENSIONS:
+		extensions, err := p.ctx.dbDriver.Extensions()
+		if err != nil {
+			return err
+		}
+		header := []string{"Extension Name", "Version", "Schema Name"}
+		data := [][]string{}
+		for _, e := range extensions {
+			data = append(data, []string{e.Name, e.Version, e.SchemaName})
+		}
+		return p.listOutput(header, data)
+
 	case SCHEMA_ITEMS:
 		return p.renderSchemaItemInfos(p.ctx.dbDriver.SchemaItems, instr, "Item")
 
@@ -296,6 +371,30 @@ func (p *processor) executeList(instr *instruction) error {
 
 	case TYPES:
 		return p.renderSchemaItemInfos(p.ctx.dbDriver.Types, instr, "Type")
+
+	case COLUMNS:
+		return p.renderColumns(instr)
+
+	case INDEXES:
+		return p.renderIndexes(instr)
+
+	case CONSTRAINTS:
+		return p.renderConstraints(instr)
+
+	case TRIGGERS:
+		return p.renderTriggers(instr)
+
+	case DIFF:
+		return p.renderDiff(instr)
+
+	case MIGRATIONS:
+		return p.renderMigrationStatus(instr)
+
+	case CHECKSUMS:
+		return p.renderChecksumDrift(instr)
+
+	case DROPS:
+		return p.renderDropsCheck(instr)
 	}
 
 	return fmt.Errorf("unknown item type '%s'", itemType)
@@ -327,6 +426,85 @@ func (p *processor) renderSchemaItemInfos(driverFn getSchemaItemsFn, instr *inst
 	return p.listOutput(header, data)
 }
 
+func (p *processor) renderColumns(instr *instruction) error {
+	header := []string{"Schema Name", "Table Name", "Column Name", "Ordinal", "Data Type", "Nullable", "Default",
+		"Identity", "Generated", "Comment"}
+	data := [][]string{}
+	for _, n := range instr.params[TABLE_NAMES].([]string) {
+		schemaName, tableName, err := parseSchemaItemName(n)
+		if err != nil {
+			return err
+		}
+		columns, err := p.ctx.dbDriver.Columns(schemaName, tableName)
+		if err != nil {
+			return err
+		}
+		for _, c := range columns {
+			data = append(data, []string{c.SchemaName, c.TableName, c.ColumnName, strconv.Itoa(c.Ordinal), c.DataType,
+				strconv.FormatBool(c.Nullable), c.Default, c.Identity, c.Generated, c.Comment})
+		}
+	}
+	return p.listOutput(header, data)
+}
+
+func (p *processor) renderIndexes(instr *instruction) error {
+	header := []string{"Schema Name", "Table Name", "Index Name", "Columns", "Unique", "Method", "Predicate"}
+	data := [][]string{}
+	for _, n := range instr.params[TABLE_NAMES].([]string) {
+		schemaName, tableName, err := parseSchemaItemName(n)
+		if err != nil {
+			return err
+		}
+		indexes, err := p.ctx.dbDriver.Indexes(schemaName, tableName)
+		if err != nil {
+			return err
+		}
+		for _, i := range indexes {
+			data = append(data, []string{i.SchemaName, i.TableName, i.IndexName, strings.Join(i.Columns, ", "),
+				strconv.FormatBool(i.Unique), i.Method, i.Predicate})
+		}
+	}
+	return p.listOutput(header, data)
+}
+
+func (p *processor) renderConstraints(instr *instruction) error {
+	header := []string{"Schema Name", "Table Name", "Constraint Name", "Constraint Type", "Definition"}
+	data := [][]string{}
+	for _, n := range instr.params[TABLE_NAMES].([]string) {
+		schemaName, tableName, err := parseSchemaItemName(n)
+		if err != nil {
+			return err
+		}
+		constraints, err := p.ctx.dbDriver.Constraints(schemaName, tableName)
+		if err != nil {
+			return err
+		}
+		for _, c := range constraints {
+			data = append(data, []string{c.SchemaName, c.TableName, c.ConstraintName, c.ConstraintType, c.Definition})
+		}
+	}
+	return p.listOutput(header, data)
+}
+
+func (p *processor) renderTriggers(instr *instruction) error {
+	header := []string{"Schema Name", "Table Name", "Trigger Name", "Timing", "Events", "Function"}
+	data := [][]string{}
+	for _, n := range instr.params[TABLE_NAMES].([]string) {
+		schemaName, tableName, err := parseSchemaItemName(n)
+		if err != nil {
+			return err
+		}
+		triggers, err := p.ctx.dbDriver.Triggers(schemaName, tableName)
+		if err != nil {
+			return err
+		}
+		for _, t := range triggers {
+			data = append(data, []string{t.SchemaName, t.TableName, t.TriggerName, t.Timing, strings.Join(t.Events, " OR "), t.Function})
+		}
+	}
+	return p.listOutput(header, data)
+}
+
 func levelOrDryRun(ctx *Context, level log.LogLevel) log.LogLevel {
 	if ctx.isListCommand() {
 		return log.LEVEL_DEBUG
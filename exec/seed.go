@@ -7,7 +7,6 @@ import (
 	"github.com/mattn/go-shellwords"
 	"github.com/nrfta/ddsl/log"
 	"github.com/nrfta/ddsl/parser"
-	"io/ioutil"
 	"path"
 	"sort"
 	"strings"
@@ -305,30 +304,31 @@ func (p *preprocessor) preprocessSeedPath(pathPattern string, paramsMap map[stri
 	count := 0
 
 	for _, dir := range dirs {
-		filePaths, err := p.sourceDriver.ReadFiles(dir, filePattern)
+		fileReaders, err := p.readFiles(dir, filePattern)
 		if err != nil {
 			return 0, err
 		}
 
-		for _, filePath := range filePaths {
+		for _, fr := range fileReaders {
+			filePath := fr.Name
 			ext := path.Ext(filePath)
 			switch ext {
 			case ".csv":
 				if !strings.Contains(relativePath, "/tables/") {
 					return count, fmt.Errorf("only tables can be seeded with CSV: %s", filePath)
 				}
-				log.Debug("preprocessing CSV seed %s", filePath)
+				log.Debug("preprocessing CSV seed %s", p.ctx.describeFile(filePath))
 				paramsMap[FILE_PATH] = filePath
 				p.ctx.addInstructionWithParams(INSTR_CSV_FILE, paramsMap)
 				count++
 			case ".sql": // TODO ".sh", ".ddsl":
-				log.Debug("preprocessing SQL seed %s", filePath)
+				log.Debug("preprocessing SQL seed %s", p.ctx.describeFile(filePath))
 				paramsMap[FILE_PATH] = filePath
 				p.ctx.addInstructionWithParams(INSTR_SQL_FILE, paramsMap)
 				count++
 			case ".ddsl":
-				log.Debug("preprocessing DDSL seed %s", filePath)
-				commandBytes, err := ioutil.ReadFile(filePath)
+				log.Debug("preprocessing DDSL seed %s", p.ctx.describeFile(filePath))
+				commandBytes, err := readAll(fr)
 				if err != nil {
 					return count, err
 				}
@@ -398,7 +398,7 @@ func (p *preprocessor) getSeedNames(relativeFilePathPattern string, with, withou
 
 	seedNames := []string{}
 	for _, d := range dirs {
-		filePaths, err := p.sourceDriver.ReadFiles(d, filePattern)
+		fileReaders, err := p.readFiles(d, filePattern)
 		if err != nil {
 			return nil, err
 		}
@@ -412,9 +412,9 @@ func (p *preprocessor) getSeedNames(relativeFilePathPattern string, with, withou
 			without = []string{}
 		}
 
-		for _, filePath := range filePaths {
-			i := strings.Index(path.Base(filePath), ".")
-			seedName := path.Base(filePath)[:i]
+		for _, fr := range fileReaders {
+			i := strings.Index(path.Base(fr.Name), ".")
+			seedName := path.Base(fr.Name)[:i]
 			if sliceutil.Contains(seedNames, seedName) {
 				continue
 			}
@@ -1,16 +1,18 @@
 module github.com/nrfta/ddsl
 
-go 1.13
+go 1.16
 
 require (
 	github.com/c-bata/go-prompt v0.2.3
 	github.com/dhui/dktest v0.3.1
 	github.com/forestgiant/sliceutil v0.0.0-20160425183142-94783f95db6c
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/golang-migrate/migrate v3.5.4+incompatible
 	github.com/lib/pq v1.3.0
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/mattn/go-runewidth v0.0.8 // indirect
 	github.com/mattn/go-shellwords v1.0.9
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/mattn/go-tty v0.0.3 // indirect
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/onsi/ginkgo v1.10.3
@@ -123,6 +123,9 @@ var commandSpec = `ddsl,Top level command,primary
       except,Comma-delimited list of schemas to exclude,optional
         in,Comma delimited list of schemas
           -exclude_schemas,Comma-delimited list of schemas
+    columns,List all columns on one or more tables or views,primary
+      on,Comma delimited list of tables and views
+        -include_tables_and_views,Comma-delimited list of tables and views
     indexes,List all indexes on one or more tables or views,primary
       on,Comma delimited list of tables and views
         -include_tables_and_views,Comma-delimited list of tables and views
@@ -138,6 +141,65 @@ var commandSpec = `ddsl,Top level command,primary
       except,Comma-delimited list of schemas to exclude,optional
         in,Comma delimited list of schemas
           -exclude_schemas,Comma-delimited list of schemas
+  diff,Compare the source with the database,root
+    schemas,Compare schemas,primary
+      except,Comma-delimited list of schemas to exclude,optional
+        -exclude_schemas,Comma-delimited list of schemas to exclude
+    schema-items,Compare schema items in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+    tables,Compare tables in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+    views,Compare views in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+    functions,Compare functions in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+    procedures,Compare procedures in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+    types,Compare types in one or more schemas,primary
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
+  extract,Write the database definitions to the source,root
+    schemas,Extract all schemas,primary
+      except,Comma-delimited list of schemas to exclude,optional
+        -exclude_schemas,Comma-delimited list of schemas to exclude
+    schema,Extract one or more schemas,primary
+      -include_schemas,Comma-delimited list of schemas
+  generate,Generate files in the source,root
+    drops,Generate the drop files of create files,primary
+      check,Check the drop files instead of generating them,optional
+        in,Comma delimited list of schemas,optional
+          -include_schemas,Comma-delimited list of schemas
+        except,Comma-delimited list of schemas to exclude,optional
+          in,Comma delimited list of schemas
+            -exclude_schemas,Comma-delimited list of schemas
+      in,Comma delimited list of schemas,optional
+        -include_schemas,Comma-delimited list of schemas
+      except,Comma-delimited list of schemas to exclude,optional
+        in,Comma delimited list of schemas
+          -exclude_schemas,Comma-delimited list of schemas
   migrate,Top level migrate command,root
     up,Migrate the database up in version,primary
       -number_of_versions,Number of versions to migrate
@@ -145,6 +207,16 @@ var commandSpec = `ddsl,Top level command,primary
       -number_of_versions,Number of versions to migrate
     top,Migrate the database to the latest version,primary
     bottom,Migrate the database to the earliest version,primary
+    to,Migrate the database up or down to a version,primary
+      -version,Version to migrate to
+    redo,Migrate the database down and back up again,primary
+      -number_of_versions,Number of versions to redo
+    baseline,Mark migrations up to a version as applied without running them,primary
+      -version,Version to baseline to
+    new,Create the up and down files of a new migration,primary
+      -title,Title of the migration
+    status,Show the status of each migration,primary
+    verify,Verify applied migrations have not changed,primary
   seed,Seed the database from source,root
     cmd,Seed the database by running a shell command,primary
       -command,Shell command to run
@@ -103,14 +103,35 @@ var positiveTests = []posTestSpec{
 	{"list procedures except in foo_schema,bar_schema", "list", "procedures", "except in", []string{}, []string{"foo_schema", "bar_schema"}},
 	{"list constraints on foo_schema.foo_table", "list", "constraints", "on", []string{}, []string{"foo_schema.foo_table"}},
 	{"list constraints on foo_schema.foo_table,bar_schema.bar_table", "list", "constraints", "on", []string{}, []string{"foo_schema.foo_table", "bar_schema.bar_table"}},
+	{"list columns on foo_schema.foo_table", "list", "columns", "on", []string{}, []string{"foo_schema.foo_table"}},
+	{"list columns on foo_schema.foo_table,bar_schema.bar_view", "list", "columns", "on", []string{}, []string{"foo_schema.foo_table", "bar_schema.bar_view"}},
 	{"list indexes on foo_schema.foo_table", "list", "indexes", "on", []string{}, []string{"foo_schema.foo_table"}},
 	{"list indexes on foo_schema.foo_table,bar_schema.bar_table", "list", "indexes", "on", []string{}, []string{"foo_schema.foo_table", "bar_schema.bar_table"}},
 	{"list triggers on foo_schema.foo_table", "list", "triggers", "on", []string{}, []string{"foo_schema.foo_table"}},
 	{"list triggers on foo_schema.foo_table,bar_schema.bar_table", "list", "triggers", "on", []string{}, []string{"foo_schema.foo_table", "bar_schema.bar_table"}},
+	{"diff schemas", "diff", "schemas", "", []string{}, []string{}},
+	{"diff schemas except public", "diff", "schemas", "except", []string{}, []string{"public"}},
+	{"diff schema-items", "diff", "schema-items", "", []string{}, []string{}},
+	{"diff tables in foo_schema", "diff", "tables", "in", []string{}, []string{"foo_schema"}},
+	{"diff types except in foo_schema,bar_schema", "diff", "types", "except in", []string{}, []string{"foo_schema", "bar_schema"}},
+	{"extract schemas", "extract", "schemas", "", []string{}, []string{}},
+	{"extract schemas except public", "extract", "schemas", "except", []string{}, []string{"public"}},
+	{"extract schema foo_schema,bar_schema", "extract", "schema", "", []string{}, []string{"foo_schema", "bar_schema"}},
+	{"generate drops", "generate", "drops", "", []string{}, []string{}},
+	{"generate drops in foo_schema", "generate", "drops", "in", []string{}, []string{"foo_schema"}},
+	{"generate drops check", "generate", "drops", "check", []string{}, []string{}},
+	{"generate drops check except in foo_schema,bar_schema", "generate", "drops", "check except in", []string{}, []string{"foo_schema", "bar_schema"}},
 	{"migrate up 1", "migrate", "up", "", []string{}, []string{"1"}},
 	{"migrate down 1", "migrate", "down", "", []string{}, []string{"1"}},
 	{"migrate top", "migrate", "top", "", []string{}, []string{}},
 	{"migrate bottom", "migrate", "bottom", "", []string{}, []string{}},
+	{"migrate to 2", "migrate", "to", "", []string{}, []string{"2"}},
+	{"migrate redo", "migrate", "redo", "", []string{}, []string{}},
+	{"migrate redo 2", "migrate", "redo", "", []string{}, []string{"2"}},
+	{"migrate baseline 2", "migrate", "baseline", "", []string{}, []string{"2"}},
+	{"migrate new add_foo_table", "migrate", "new", "", []string{}, []string{"add_foo_table"}},
+	{"migrate status", "migrate", "status", "", []string{}, []string{}},
+	{"migrate verify", "migrate", "verify", "", []string{}, []string{}},
 	{`seed cmd "python3 foo.py"`, "seed", "cmd", "", []string{}, []string{"python3 foo.py"}},
 	{"seed database", "create", "database", "", []string{}, []string{}},
 	{"seed database with foo_seed", "seed", "database", "with", []string{}, []string{"foo_seed"}},